@@ -0,0 +1,145 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Benchmark is a generic case-driven benchmarking function that accepts
+// a slice of cases and either 1 or 2 functions to be benchmarked. A
+// sub-benchmark is run for each case, using the inputs provided in the case.
+//
+// If 2 functions are provided, then each is benchmarked in turn for every
+// case and their timings are reported side by side, as for BenchmarkDiff
+// but without checking their outputs or flagging slow cases.
+//
+// The cases have the same structure as those passed to Test, so the same
+// table can be used for both testing and benchmarking. Outputs provided
+// in the cases are optional and are ignored.
+//
+// The arguments for each case are built before the timed loop, so the
+// reported timings exclude the reflection needed to construct them.
+func Benchmark(b *testing.B, cases Cases, funcs ...Func) {
+	cvs, nc, nfc, err := parseCases(cases)
+	if err != nil {
+		b.Fatal(err)
+	}
+	f1v, f2v, err := parseFuncs(funcs...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if !f2v.IsNil() {
+		benchmarkDiff(b, newConfig(), math.Inf(1), false, cvs, nc, nfc, f1v, f2v)
+		return
+	}
+	nIn := f1v.Type().NumIn()
+	nOut := f1v.Type().NumOut()
+
+	if nfc-1 != nIn+nOut && nfc-1 != nIn { // outputs are optional
+		b.Fatalf("wrong number of input slices. Got %v, want %v", nfc-1, nIn)
+	}
+
+	for i := 0; i < nc; i++ {
+		subbenchmark(b, cvs.Index(i), f1v, nIn)
+	}
+}
+
+// subbenchmark runs a sub-benchmark for a case.
+func subbenchmark(b *testing.B, cv, fv reflect.Value, nIn int) {
+	in := sliceFrom(cv, 1, nIn)
+	b.Run(name(cv), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			fv.Call(in)
+		}
+	})
+}
+
+// BenchmarkDiff is a generic case-driven benchmarking function that
+// compares 2 implementations of the same function, f1 and f2, on a slice
+// of cases. Typically f1 is an optimised implementation and f2 is a
+// reference implementation.
+//
+// For each case, the outputs of f1 and f2 are first checked to be equal
+// within the numerical tolerance, as in Test. Both functions are then
+// benchmarked, f2 first, and the timing of f1 is reported side by side with
+// that of f2 (f2-ns/op) and the ratio of the two (f1/f2). A case fails if the
+// ratio exceeds factor, i.e. if f1 is more than factor times slower than f2.
+func BenchmarkDiff(b *testing.B, tolerance interface{}, factor float64, cases Cases, f1, f2 Func) {
+	c := newConfig(WithTolerance(tolerance))
+	cvs, nc, nfc, err := parseCases(cases)
+	if err != nil {
+		b.Fatal(err)
+	}
+	f1v, f2v, err := parseFuncs(f1, f2)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkDiff(b, c, factor, true, cvs, nc, nfc, f1v, f2v)
+}
+
+// benchmarkDiff runs a differential sub-benchmark for each case. If check is
+// true, the outputs of f1 and f2 are compared before they are benchmarked.
+func benchmarkDiff(b *testing.B, c *config, factor float64, check bool, cvs reflect.Value, nc, nfc int, f1v, f2v reflect.Value) {
+	nIn := f1v.Type().NumIn()
+	nOut := f1v.Type().NumOut()
+
+	if nfc-1 != nIn+nOut && nfc-1 != nIn { // outputs are optional
+		b.Fatalf("wrong number of input slices. Got %v, want %v", nfc-1, nIn)
+	}
+
+	for i := 0; i < nc; i++ {
+		subbenchmarkDiff(b, cvs.Index(i), f1v, f2v, nIn, nOut, c, factor, check)
+	}
+}
+
+// subbenchmarkDiff runs a differential sub-benchmark for a case.
+func subbenchmarkDiff(b *testing.B, cv, f1v, f2v reflect.Value, nIn, nOut int, c *config, factor float64, check bool) {
+	c = caseConfig(cv, c)
+	in := sliceFrom(cv, 1, nIn)
+	b.Run(name(cv), func(b *testing.B) {
+		if check {
+			res := f1v.Call(in)
+			out := f2v.Call(in)
+			for i := 0; i < nOut; i++ {
+				if err := compareOutput(i, res[i], out[i], outputConfig(in, out[i], c)).Err; err != nil {
+					b.Error(err)
+				}
+			}
+		}
+
+		ns2 := timeFunc(b, "f2", f2v, in, 0)
+		ns1 := timeFunc(b, "f1", f1v, in, ns2)
+		if ns1 == 0 || ns2 == 0 { // one of the functions was filtered out by -bench
+			return
+		}
+		if ratio := ns1 / ns2; ratio > factor {
+			b.Errorf("f1 slower than f2. Got f1/f2 = %.3g, want at most %v", ratio, factor)
+		}
+	})
+}
+
+// timeFunc runs a sub-benchmark of fv called with the arguments in,
+// and returns the time taken per call in nanoseconds. If ref is non-zero,
+// it is reported alongside the timing as f2-ns/op, together with the ratio
+// of the two as f1/f2.
+func timeFunc(b *testing.B, name string, fv reflect.Value, in []reflect.Value, ref float64) (ns float64) {
+	b.Run(name, func(b *testing.B) {
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			fv.Call(in)
+		}
+		// the final run has the largest b.N and so the most accurate timing
+		ns = float64(time.Since(start).Nanoseconds()) / float64(b.N)
+		if ref > 0 {
+			b.ReportMetric(ref, "f2-ns/op")
+			b.ReportMetric(ns/ref, "f1/f2")
+		}
+	})
+	return
+}
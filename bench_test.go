@@ -0,0 +1,61 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func BenchmarkBenchmark_Funcs(b *testing.B) {
+	cases := []struct {
+		Label         string
+		In1, In2, Out float64
+	}{
+		{"Small", 3, 4, 5},
+		{"Large", 3e200, 4e200, 5e200},
+	}
+	Test(b, 1e-15, cases, math.Hypot)
+	b.ResetTimer()
+	Benchmark(b, cases, math.Hypot)
+}
+
+func BenchmarkBenchmark_NoOutputs(b *testing.B) {
+	cases := []struct {
+		Label string
+		In    int
+	}{
+		{"1", 1},
+		{"MaxInt", math.MaxInt64},
+	}
+	Benchmark(b, cases, strconv.Itoa)
+}
+
+func BenchmarkBenchmark_TwoFuncs(b *testing.B) {
+	cases := []struct {
+		Label    string
+		In1, In2 float64
+	}{
+		{"Small", 3, 4},
+		{"Large", 3e200, 4e200},
+	}
+	hypot := func(x, y float64) float64 { return math.Sqrt(x*x + y*y) }
+	Benchmark(b, cases, hypot, math.Hypot)
+}
+
+func BenchmarkBenchmarkDiff(b *testing.B) {
+	cases := []struct {
+		Label         string
+		In1, In2, Out float64
+	}{
+		{"Small", 3, 4, 5},
+		{"Medium", 3e10, 4e10, 5e10},
+	}
+	hypot := func(x, y float64) float64 { return math.Sqrt(x*x + y*y) }
+	BenchmarkDiff(b, 1e-15, 10, cases, hypot, math.Hypot)
+}
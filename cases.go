@@ -6,7 +6,10 @@ package testutil
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // Cases represents a generic data structure for table-driven testing.
@@ -36,11 +39,44 @@ import (
 //		  {"AnotherLabel", x1, y1},
 //	   ...
 //	 }
+//
+// Cases may also have special fields, which are neither inputs nor
+// outputs and may appear anywhere after the label. A special field
+// is recognised by its struct tag, e.g. `testutil:"panic"`, or by its
+// reserved name if it has one of the types listed. The special fields are:
+//
+//	Panic      `testutil:"panic"`  the panic expected from the function (see Test)
+//	                               interface{}, bool or string
+//	Tolerance  `testutil:"tol"`    the tolerance for the case, overriding that of Test
+//	                               interface{}, Tolerance or ULP
+//
+// A field with a reserved name but another type, e.g. a Tolerance float64
+// output, is not special; a tag is needed to make it special. Special fields
+// must be exported.
+// The Tolerance field accepts the same values as the tolerance argument of Test,
+// and may be an interface{} so that cases can mix a number, a ULP and a Tolerance.
+// If it is zero or nil, then the tolerance of Test is used for the case.
 type Cases interface{}
 
+// specialFields maps the tags of the special fields of a case to their names.
+var specialFields = map[string]string{
+	"panic": "Panic",
+	"tol":   "Tolerance",
+}
+
+// specialTypes maps the tags of the special fields of a case to the types
+// with which a field is recognised as special by its name alone.
+var specialTypes = map[string][]reflect.Type{
+	"panic": {interfaceType, reflect.TypeOf(false), reflect.TypeOf("")},
+	"tol":   {interfaceType, toleranceType, reflect.TypeOf(ULP(0))},
+}
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
 // parseCases converts cases reflect values and performs basic validation checks.
 // If any checks fail, parse panics.
-// nc is the number of cases, nf is the number of fields in a case (label + inputs + outputs)
+// nc is the number of cases, nf is the number of fields in a case (label + inputs + outputs),
+// excluding any special fields.
 func parseCases(cases Cases) (casesv reflect.Value, ncases, nfields int, err error) {
 	casesv = reflect.ValueOf(cases)
 	if !casesv.IsValid() {
@@ -70,8 +106,8 @@ func parseCases(cases Cases) (casesv reflect.Value, ncases, nfields int, err err
 	}
 
 	// Ensure cases have at least 1 field, for the label.
-	nfields = casesv.Index(0).NumField()
-	if nfields == 0 {
+	nfields = len(dataFields(casesv.Type().Elem()))
+	if casesv.Index(0).NumField() == 0 {
 		err = fmt.Errorf("too few fields in cases. Got 0, want at least 1")
 		return
 	}
@@ -82,6 +118,15 @@ func parseCases(cases Cases) (casesv reflect.Value, ncases, nfields int, err err
 		err = fmt.Errorf("invalid type for first field. Got %v, want %v", kfc, "string")
 		return
 	}
+
+	// Ensure the special fields are exported, so that they can be read.
+	t := casesv.Type().Elem()
+	for i := 1; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath != "" && specialName(f) != "" {
+			err = fmt.Errorf("unexported special field %v. Want an exported field", f.Name)
+			return
+		}
+	}
 	return
 }
 
@@ -89,18 +134,65 @@ func parseCases(cases Cases) (casesv reflect.Value, ncases, nfields int, err err
 func name(cv reflect.Value) string { return cv.Field(0).String() }
 
 // sliceFrom creates a slice of the fields of the case c.
-// The returned slice contains field start to start+n.
+// The returned slice contains field start to start+n,
+// skipping any special fields.
 //
 // For pointer or interface fields, the underlying value
 // is used in the output slice.
 func sliceFrom(cv reflect.Value, start, n int) []reflect.Value {
+	fields := dataFields(cv.Type())
 	v := make([]reflect.Value, n)
 	for i := 0; i < n; i++ {
-		v[i] = indirect(cv.Field(start + i))
+		v[i] = indirect(cv.Field(fields[start+i]))
 	}
 	return v
 }
 
+// dataFields returns the indices of the fields of the case type t
+// which are not special fields, i.e. the label, inputs and outputs.
+func dataFields(t reflect.Type) (fields []int) {
+	for i := 0; i < t.NumField(); i++ {
+		if i == 0 || specialName(t.Field(i)) == "" {
+			fields = append(fields, i)
+		}
+	}
+	return
+}
+
+// specialName returns the tag of the special field f,
+// or an empty string if f is not a special field.
+func specialName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("testutil"); ok {
+		if _, ok := specialFields[tag]; ok {
+			return tag
+		}
+	}
+	for tag, name := range specialFields {
+		if f.Name != name {
+			continue
+		}
+		for _, t := range specialTypes[tag] {
+			if f.Type == t {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// special returns the value of the special field with the given tag in the
+// case cv, or an invalid value if the case does not have one. For pointer or
+// interface fields, the underlying value is returned.
+func special(cv reflect.Value, tag string) reflect.Value {
+	t := cv.Type()
+	for i := 1; i < t.NumField(); i++ {
+		if specialName(t.Field(i)) == tag {
+			return indirect(cv.Field(i))
+		}
+	}
+	return reflect.Value{}
+}
+
 // indirect returns the value referred to by
 // a pointer or interface, or the value itself otherwise.
 func indirect(v reflect.Value) reflect.Value {
@@ -109,3 +201,100 @@ func indirect(v reflect.Value) reflect.Value {
 	}
 	return v
 }
+
+// caseLiteral formats a case with the given label and values (inputs
+// followed by outputs) as a Go composite literal that can be pasted
+// into a slice of Cases.
+func caseLiteral(label string, values []interface{}) string {
+	s := make([]string, len(values)+1)
+	s[0] = strconv.Quote(label)
+	for i, v := range values {
+		s[i+1] = literal(reflect.ValueOf(v), true)
+	}
+	return "{" + strings.Join(s, ", ") + "},"
+}
+
+// literal formats the value v as Go source. If typed is true, values
+// whose type differs from the default type of the corresponding untyped
+// constant are converted explicitly, e.g. int64(1), so that the literal
+// has the right type when assigned to an interface.
+func literal(v reflect.Value, typed bool) (s string) {
+	if !v.IsValid() {
+		return "nil"
+	}
+	t := v.Type()
+	switch kind := v.Kind(); kind {
+	case reflect.Bool:
+		s = strconv.FormatBool(v.Bool())
+	case reflect.String:
+		s = strconv.Quote(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = floatLiteral(v.Float(), t.Bits())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		bits := t.Bits() / 2
+		s = "complex(" + floatLiteral(real(c), bits) + ", " + floatLiteral(imag(c), bits) + ")"
+	case reflect.Slice, reflect.Array:
+		if kind == reflect.Slice && v.IsNil() {
+			return "nil"
+		}
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = literal(v.Index(i), false)
+		}
+		return t.String() + "{" + strings.Join(elems, ", ") + "}"
+	case reflect.Struct:
+		fields := make([]string, v.NumField())
+		for i := range fields {
+			fields[i] = t.Field(i).Name + ": " + literal(v.Field(i), false)
+		}
+		return t.String() + "{" + strings.Join(fields, ", ") + "}"
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return "nil"
+		}
+		if kind == reflect.Interface {
+			return literal(v.Elem(), true)
+		}
+		fallthrough
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+
+	// untyped constants default to bool, string, int, float64 and complex128
+	switch t {
+	case reflect.TypeOf(false), reflect.TypeOf(""), reflect.TypeOf(0), floatType, complexType:
+		return
+	}
+	if typed {
+		s = t.String() + "(" + s + ")"
+	}
+	return
+}
+
+// floatLiteral formats the float x of the given bit size as Go source.
+func floatLiteral(x float64, bits int) (s string) {
+	switch {
+	case math.IsNaN(x):
+		s = "math.NaN()"
+	case math.IsInf(x, 1):
+		s = "math.Inf(1)"
+	case math.IsInf(x, -1):
+		s = "math.Inf(-1)"
+	}
+	if s != "" {
+		if bits == 32 {
+			s = "float32(" + s + ")"
+		}
+		return s
+	}
+	s = strconv.FormatFloat(x, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s += "."
+	}
+	return s
+}
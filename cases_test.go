@@ -62,6 +62,13 @@ func TestParseCases(t *testing.T) {
 	}{
 		{1., 2.},
 	}
+	casesUnexported := []struct {
+		Label   string
+		In, Out float64
+		tol     float64 `testutil:"tol"`
+	}{
+		{"", 1., 2., 0.},
+	}
 
 	cases := []struct {
 		Label           string
@@ -78,6 +85,7 @@ func TestParseCases(t *testing.T) {
 		{"Bad", casesNotStructs, 1, 0, fmt.Errorf("wrong input type. Got []map, want []struct")},
 		{"Bad", casesEmpty, 2, 0, fmt.Errorf("too few fields in cases. Got 0, want at least 1")},
 		{"Bad", casesNoLabel, 1, 2, fmt.Errorf("invalid type for first field. Got float64, want string")},
+		{"Bad", casesUnexported, 1, 3, fmt.Errorf("unexported special field tol. Want an exported field")},
 	}
 
 	for _, c := range cases {
@@ -89,3 +97,22 @@ func TestParseCases(t *testing.T) {
 		})
 	}
 }
+
+func TestCaseLiteral(t *testing.T) {
+	type point struct{ X, Y float64 }
+
+	cases := []struct {
+		Label  string
+		Name   string
+		Values []interface{}
+		Out    string
+	}{
+		{"Numbers", "a", []interface{}{1., 2, 3.5}, `{"a", 1., 2, 3.5},`},
+		{"Typed", "b", []interface{}{float32(1), int64(2), uint8(3)}, `{"b", float32(1.), int64(2), uint8(3)},`},
+		{"Special", "c", []interface{}{nan, -inf, complex(1, inf)}, `{"c", math.NaN(), math.Inf(-1), complex(1., math.Inf(1))},`},
+		{"Nil", "d", []interface{}{nil, "x", true}, `{"d", nil, "x", true},`},
+		{"Slices", "e", []interface{}{[]float32{1, float32(nan)}}, `{"e", []float32{1., float32(math.NaN())}},`},
+		{"Structs", "f", []interface{}{point{0.5, 2}}, `{"f", testutil_test.point{X: 0.5, Y: 2.}},`},
+	}
+	Test(t, nil, cases, CaseLiteral)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+// Result represents the result of comparing one output of a case.
+type Result struct {
+	// Label is the label of the case.
+	Label string
+
+	// Case is the index of the case in the slice of cases.
+	Case int
+
+	// Output is the index of the output, or -1 if the result
+	// is for a panic, in which case Got is the panic value and
+	// Want is the expected panic, if any.
+	Output int
+
+	// Got is the actual output and Want is the expected output.
+	Got, Want interface{}
+
+	// AbsoluteError and RelativeError are the errors in Got relative to
+	// Want if they are numerical, as in EqualResult, or nil otherwise.
+	AbsoluteError, RelativeError interface{}
+
+	// Mismatches holds every part of Got which does not equal Want,
+	// as in EqualResult, if the WithMismatches option is used.
+	Mismatches []Mismatch
+
+	// Summary holds summary statistics of the errors in Got,
+	// as in EqualResult, if the WithSummary option is used.
+	Summary *Summary
+
+	// Ok is true if Got equals Want.
+	Ok bool
+
+	// Err describes the failure if Ok is false, as reported by Test.
+	Err error
+}
+
+// Report represents the results of every output of every case
+// checked by Check, in order.
+type Report []Result
+
+// Ok reports whether every result in r is Ok.
+func (r Report) Ok() bool {
+	for _, res := range r {
+		if !res.Ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Failures returns the results in r which are not Ok.
+func (r Report) Failures() (f Report) {
+	for _, res := range r {
+		if !res.Ok {
+			f = append(f, res)
+		}
+	}
+	return
+}
+
+// errors returns the errors of the results in r which are not Ok.
+func (r Report) errors() (errs []error) {
+	for _, res := range r.Failures() {
+		errs = append(errs, res.Err)
+	}
+	return
+}
+
+// Check is a generic case-driven checking function that performs exactly
+// the same comparisons as Test, but returns a Report of the result of every
+// output of every case instead of failing a test. This allows case tables
+// to be checked outside of go test.
+//
+// An error is returned if the cases or the function(s) are invalid.
+func Check(tolerance interface{}, cases Cases, funcs ...Func) (Report, error) {
+	return CheckWith(cases, funcs, WithTolerance(tolerance))
+}
+
+// CheckWith is like Check, but accepts the same options as TestWith.
+// The options that only apply to tests, such as WithReporter, are ignored.
+func CheckWith(cases Cases, funcs []Func, opts ...Option) (rep Report, err error) {
+	c := newConfig(opts...)
+	tab, err := newTable(cases, funcs)
+	if err != nil {
+		return
+	}
+
+	eval := func(i int) []Result { return tab.evaluate(i, c) }
+	if c.workers > 1 {
+		var stop func()
+		eval, stop = parallel(tab.nc, c.workers, eval)
+		defer stop()
+	}
+
+	failures := 0
+	for i := 0; i < tab.nc; i++ {
+		if c.maxFailures > 0 && failures == c.maxFailures {
+			return
+		}
+		rs := Report(eval(i))
+		if !rs.Ok() {
+			failures++
+		}
+		rep = append(rep, rs...)
+	}
+	return
+}
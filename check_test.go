@@ -0,0 +1,62 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out float64
+	}{
+		{"Zero", 0, 0},
+		{"One", 1, 1.1},
+		{"Four", 4, 2},
+	}
+
+	rep, err := Check(0.01, cases, math.Sqrt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, exp := 1., 1.1
+	want := Report{
+		{Label: "Zero", Case: 0, Output: 0, Got: 0., Want: 0., AbsoluteError: 0., RelativeError: 0., Ok: true},
+		{Label: "One", Case: 1, Output: 0, Got: 1., Want: 1.1, AbsoluteError: got - exp, RelativeError: (got - exp) / exp,
+			Err: fmt.Errorf("[0]: Got 1, want 1.1 (δ=-0.09090909090909098)")},
+		{Label: "Four", Case: 2, Output: 0, Got: 2., Want: 2., AbsoluteError: 0., RelativeError: 0., Ok: true},
+	}
+	if res := Equal(rep, want, nil); !res.Ok {
+		t.Errorf("Error: wanted %v, got %v", want, rep)
+	}
+	if rep.Ok() {
+		t.Errorf("Error: wanted report to fail")
+	}
+	if f := rep.Failures(); len(f) != 1 || f[0].Label != "One" {
+		t.Errorf("Error: wanted 1 failure, got %v", f)
+	}
+}
+
+func TestCheck_Errors(t *testing.T) {
+	cases := []struct {
+		Label         string
+		In1, In2, In3 float64
+	}{
+		{"", 1, 2, 3},
+	}
+	_, err := Check(nil, cases, math.Sqrt)
+	if want := fmt.Errorf("wrong number of input/output slices. Got 3, want 2"); !Equal(&err, &want, nil).Ok {
+		t.Errorf("Error: wanted %v, got %v", want, err)
+	}
+	_, err = Check(nil, cases, math.Sqrt, math.Sqrt)
+	if want := fmt.Errorf("wrong number of input slices. Got 3, want 1"); !Equal(&err, &want, nil).Ok {
+		t.Errorf("Error: wanted %v, got %v", want, err)
+	}
+}
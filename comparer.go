@@ -0,0 +1,106 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Equaler is implemented by types which define their own equality within a
+// tolerance, e.g. intervals or polynomials, for which comparing field by
+// field is wrong. When comparing values x and y of the same type, if x
+// implements Equaler, then x equals y if x.EqualWithin(y, tol) is true.
+//
+// tol is the relative bound of the tolerance, or the absolute bound
+// if there is no relative bound. EqualWithin can use Equal, but not on
+// values of the same type as x, which would call EqualWithin again.
+type Equaler interface {
+	EqualWithin(other interface{}, tol float64) bool
+}
+
+var equalerType = reflect.TypeOf((*Equaler)(nil)).Elem()
+
+// comparers holds the registered comparison functions by type, as a
+// map[reflect.Type]reflect.Value which is replaced rather than modified
+// by each registration, so that it can be read without locking.
+var comparers atomic.Value
+
+// comparersMu serialises the registrations of comparers.
+var comparersMu sync.Mutex
+
+// RegisterComparer registers the function f to compare values of a type T
+// wherever they appear, before any other comparison. f must have the form
+//
+//	func(x, y T, tol float64) bool
+//	func(x, y T, tol Tolerance) bool
+//
+// and report whether x (actual) equals y (expected) within tol, which is
+// the tolerance of the comparison, or its relative bound as for Equaler.
+// A later registration for T replaces an earlier one, and a nil f of the
+// same form removes it.
+// RegisterComparer panics if f does not have either form. Like EqualWithin,
+// f can use Equal, but not on values of type T, which would call f again.
+func RegisterComparer(f interface{}) {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func {
+		panic(fmt.Sprintf("testutil: invalid comparer of kind %v, want func", fv.Kind()))
+	}
+	ft := fv.Type()
+	if ft.NumIn() != 3 || ft.NumOut() != 1 || ft.In(0) != ft.In(1) || ft.Out(0).Kind() != reflect.Bool ||
+		(ft.In(2) != floatType && ft.In(2) != toleranceType) {
+		panic(fmt.Sprintf("testutil: invalid comparer %v, want func(x, y T, tol float64 or Tolerance) bool", ft))
+	}
+
+	comparersMu.Lock()
+	defer comparersMu.Unlock()
+	old, _ := comparers.Load().(map[reflect.Type]reflect.Value)
+	m := make(map[reflect.Type]reflect.Value, len(old)+1)
+	for t, f := range old {
+		m[t] = f
+	}
+	if fv.IsNil() {
+		delete(m, ft.In(0))
+	} else {
+		m[ft.In(0)] = fv
+	}
+	comparers.Store(m)
+}
+
+var toleranceType = reflect.TypeOf(Tolerance{})
+
+// equalCustom compares xv and yv using a registered comparer for their type,
+// or the EqualWithin method of xv, and reports whether either was used.
+func equalCustom(xv, yv reflect.Value, c *config) (res EqualResult, ok bool) {
+	t := xv.Type()
+	m, _ := comparers.Load().(map[reflect.Type]reflect.Value)
+	if len(m) == 0 && t.NumMethod() == 0 {
+		// the common case, e.g. for the elements of a []float64
+		return
+	}
+	if t != yv.Type() || !xv.CanInterface() || !yv.CanInterface() {
+		return
+	}
+	if k := t.Kind(); (k == reflect.Ptr || k == reflect.Interface) && (xv.IsNil() || yv.IsNil()) {
+		return
+	}
+
+	fv, ok := m[t]
+	if ok {
+		tol := reflect.ValueOf(c.tol.scalar())
+		if fv.Type().In(2) == toleranceType {
+			tol = reflect.ValueOf(c.tol)
+		}
+		res.Ok = fv.Call([]reflect.Value{xv, yv, tol})[0].Bool()
+		return
+	}
+
+	if ok = t.NumMethod() > 0 && t.Implements(equalerType); ok {
+		res.Ok = xv.Interface().(Equaler).EqualWithin(yv.Interface(), c.tol.scalar())
+	}
+	return
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+// quaternion is equal to its negation as a rotation.
+type quaternion [4]float64
+
+func sameRotation(x, y quaternion, tol float64) bool {
+	neg := [4]float64{-y[0], -y[1], -y[2], -y[3]}
+	return Equal([4]float64(x), [4]float64(y), tol).Ok || Equal([4]float64(x), neg, tol).Ok
+}
+
+// poly is a polynomial, which is equal to another if they have the same
+// coefficients after trailing zeros are removed.
+type poly []float64
+
+func (p poly) EqualWithin(other interface{}, tol float64) bool {
+	trim := func(p poly) poly {
+		for len(p) > 0 && p[len(p)-1] == 0 {
+			p = p[:len(p)-1]
+		}
+		return p
+	}
+	return Equal([]float64(trim(p)), []float64(trim(other.(poly))), tol).Ok
+}
+
+func TestRegisterComparer(t *testing.T) {
+	RegisterComparer(sameRotation)
+	defer RegisterComparer((func(x, y quaternion, tol float64) bool)(nil))
+
+	q := quaternion{0.5, 0.5, 0.5, 0.5}
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		Out      bool
+	}{
+		{"", q, quaternion{-0.5, -0.5, -0.5, -0.5 - 1e-12}, true},
+		{"", q, quaternion{-0.5, 0.5, 0.5, 0.5}, false},
+		{"Nested", []quaternion{q}, []quaternion{{-0.5, -0.5, -0.5, -0.5}}, true},
+		{"Nested", map[string]interface{}{"q": q}, map[string]interface{}{"q": quaternion{-0.5, -0.5, -0.5, -0.5}}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := Equal(c.In1, c.In2, 1e-9); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestRegisterComparer_Tolerance(t *testing.T) {
+	RegisterComparer(func(x, y quaternion, tol Tolerance) bool { return tol.Abs == 1 })
+	defer RegisterComparer((func(x, y quaternion, tol Tolerance) bool)(nil))
+
+	if res := Equal(quaternion{}, quaternion{1}, Tolerance{Abs: 1}); !res.Ok {
+		t.Errorf("Error: wanted true, got %v", res)
+	}
+	if res := Equal(quaternion{}, quaternion{1}, nil); res.Ok {
+		t.Errorf("Error: wanted false, got %v", res)
+	}
+}
+
+func TestRegisterComparer_Invalid(t *testing.T) {
+	for _, f := range []interface{}{nil, 1., math.Sin, func(x, y int, tol int) bool { return true }} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Error: wanted panic for comparer %T", f)
+				}
+			}()
+			RegisterComparer(f)
+		}()
+	}
+}
+
+func TestEqualer(t *testing.T) {
+	cases := []struct {
+		Label    string
+		In1, In2 poly
+		Out      bool
+	}{
+		{"", poly{1, 2}, poly{1, 2, 0}, true},
+		{"", poly{1, 2 + 1e-12}, poly{1, 2}, true},
+		{"", poly{1, 2}, poly{1, 2, 3}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := Equal(c.In1, c.In2, 1e-9); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+
+	derivative := func(p poly) poly {
+		d := make(poly, len(p))
+		for i := 1; i < len(p); i++ {
+			d[i-1] = float64(i) * p[i]
+		}
+		return d
+	}
+	tests := []struct {
+		Label   string
+		In, Out poly
+	}{
+		{"", poly{1, 2, 3}, poly{2, 6}},
+	}
+	Test(t, 1e-9, tests, derivative)
+}
@@ -10,6 +10,7 @@ import (
 	"math/cmplx"
 	"math/rand"
 	"reflect"
+	"sort"
 	"testing/quick"
 	"time"
 )
@@ -23,7 +24,9 @@ type EqualResult struct {
 	// Numerical is true if x and y are numerical.
 	Numerical bool
 
-	// RelativeError is the error in x relative to y if they are numerical.
+	// RelativeError is the error in x relative to y if they are numerical,
+	// as defined by the RelativePolicy (see WithRelativePolicy), or the
+	// difference between x and y if the relative error is undefined.
 	// It is a complex number if x and y are complex numbers.
 	RelativeError reflect.Value
 
@@ -38,7 +41,9 @@ type EqualResult struct {
 	// For structs it is the index of the first field for which x does not equal y.
 	// For maps it is the index of the first key for which x does not equal y.
 	//
-	// If MissingValue is true, Position gives the index in y of the missing field or key.
+	// If MissingValue is true, Position gives the index in y of the missing field or key,
+	// or for slices compared with the WithUnordered option, of the first element
+	// of y which does not equal any unmatched element of x.
 	Position int
 
 	// LengthMismatch is true if the number of elements, fields or keys in x
@@ -46,8 +51,33 @@ type EqualResult struct {
 	LengthMismatch bool
 
 	// MissingValue is true if x and y are maps or structs and x is missing one of the keys
-	// or fields in y.
+	// or fields in y, or if x and y are slices compared with the WithUnordered option and
+	// an element of y does not equal any unmatched element of x.
 	MissingValue bool
+
+	// ULPError is the distance between x and y in units in the last place
+	// if they are numerical, i.e. the number of representable values of their
+	// type between them. For complex numbers it is the greater of the distances
+	// between the real and imaginary parts, or with the ComplexModulus policy,
+	// |x - y| in units in the last place of |y|. For integers it is |x - y|.
+	ULPError uint64
+
+	// Mismatches holds every part of x which does not equal y, up to a limit,
+	// if they are compared with the WithMismatches option, in the order found.
+	Mismatches []Mismatch
+
+	// Summary holds summary statistics of the errors if x and y are numerical
+	// slices or arrays which are compared with the WithSummary option.
+	Summary *Summary
+
+	// Counterexample holds the randomly generated arguments for which x and y
+	// disagree if they are functions, followed by the outputs of y for those
+	// arguments. It is laid out as a case for use with Test.
+	Counterexample []interface{}
+
+	// Err is non-nil if x and y could not be compared, e.g. because the
+	// testutil tag of a struct field is malformed, in which case Ok is false.
+	Err error
 }
 
 // Equal reports whether x (actual) is equal to y (expected).
@@ -57,16 +87,66 @@ type EqualResult struct {
 //	|x - y| < tolerance * |y|, for y ≠ 0 (relative error)
 //	|x| < tolerance,           for y = 0 (absolute error)
 //
+// If tolerance is a ULP, then x is instead equal to y if they are at most
+// tolerance units in the last place apart, counted in the precision of their
+// type, e.g. for float32 values there are 2^23 ULPs between 1 and 2.
+// If tolerance is a Tolerance, then x must satisfy its bounds, and if it is
+// a ToleranceFunc, then x must satisfy the bounds it returns for y.
+//
+// By default, NaNs are equal to each other, and +0 and -0 are not equal.
+// Either can be changed by the WithNaNPolicy and WithZeroPolicy options, which
+// apply to floats and to both parts of complex numbers. The WithComplexPolicy
+// option compares complex numbers by the modulus of their difference instead.
+//
 // For structured types (slice, array, struct, map), x equals y if
-// every element/field/key of x equals that in y.
+// every element/field/key of x equals that in y. The tolerance for a
+// struct field can be overridden by a testutil tag on the field of y,
+// e.g. `testutil:"abs=1e-9,rel=1e-12"`, `testutil:"ulp=4"`, `testutil:"exact"`
+// or `testutil:"ignore"` to skip the field, which applies to everything
+// the field contains. A malformed tag is reported by the Err field of
+// the result.
+// Parts of x and y can also be skipped by the WithIgnoreFields,
+// WithIgnoreMapKeys and WithIgnorePaths options.
+//
+// If a comparer is registered for the type of x and y (see RegisterComparer),
+// or x implements Equaler, then it determines whether x equals y.
+//
+// For pointer and interface types, x equals y if both are nil, or if the
+// values they refer to have the same type and are equal, so the tolerance
+// applies through pointers and interfaces. For cyclic values, a pair of
+// pointers, maps or slices is equal if it is reached again while it is
+// being compared, as for reflect.DeepEqual.
 //
 // For func types, x equals y if x(args) equals y(args) for
 // randomly generated args.
 //
-// For other types x equals y if reflect.DeepEqual(x, y) is true.
+// If y is an expected error created by ErrorIs, ErrorAs, ErrorContains or
+// ErrorMatches, then x equals y if x is an error matched by y.
+//
+// Unexported struct fields are compared in the same way as exported fields,
+// unless they are skipped with the WithUnexported option, except that funcs
+// held by them must be identical, as they cannot be called. The integers
+// within structs with no exported fields, which are the internals of opaque
+// types such as time.Time or big.Int, must also be identical, as a tolerance
+// does not apply to them.
+//
+// For other types x equals y if they are equal as for reflect.DeepEqual,
+// e.g. bools and strings must be equal and channels must be identical.
+//
+// Equal is equivalent to EqualWith with the WithTolerance option.
 func Equal(x, y, tolerance interface{}) EqualResult {
-	tol := validateTolerance(tolerance)
-	return equal(reflect.ValueOf(x), reflect.ValueOf(y), tol)
+	return EqualWith(x, y, WithTolerance(tolerance))
+}
+
+// EqualWith reports whether x (actual) is equal to y (expected) as for
+// Equal, but accepts any number of options instead of a tolerance, e.g.
+// the WithTolerance, WithNaNPolicy and WithMismatches options. By default,
+// numerical values must be exactly equal. The options that only apply to
+// tests, such as WithParallel and WithReporter, are ignored.
+func EqualWith(x, y interface{}, opts ...Option) EqualResult {
+	c := newConfig(opts...)
+	yv := reflect.ValueOf(y)
+	return compare(reflect.ValueOf(x), yv, outputConfig(nil, yv, c))
 }
 
 var floatType = reflect.ValueOf(float64(1)).Type()
@@ -75,14 +155,14 @@ var complexType = reflect.ValueOf(complex128(1)).Type()
 // equal reports whether the value represented by xv equals that which
 // is represented by yv. It recurses through nested structures to compare
 // every part for equality. Numerical values are considered equal if they
-// are equal within the specified tolerance, which means that x is equal
+// are equal within the tolerance specified in c, which means that x is equal
 // to y if and only if
 //
 //	|x - y| < tol * |y|, for y ≠ 0 (relative error)
 //	|x| < tol,           for y = 0 (absolute error)
 //
 // for floats and for both the real and imaginary parts for complex types.
-func equal(xv, yv reflect.Value, tol float64) (res EqualResult) {
+func equal(xv, yv reflect.Value, c *config) (res EqualResult) {
 	// this occurs when the expected output for y is nil, e.g. for errors,
 	// which does not have a concrete type. To avoid panicking, we cast y as
 	// a zero of type x. For the example case of errors, this would
@@ -95,6 +175,20 @@ func equal(xv, yv reflect.Value, tol float64) (res EqualResult) {
 		return
 	}
 
+	// expected errors created by ErrorIs etc. match errors rather than equal them
+	if m, ok := errorMatcherOf(yv); ok {
+		return equalError(xv, m)
+	}
+
+	// a value held in an interface, e.g. an error returned by a function,
+	// is compared by its dynamic value if the other value is not an interface
+	switch xk, yk := xv.Kind(), yv.Kind(); {
+	case xk == reflect.Interface && yk != reflect.Interface:
+		return equal(xv.Elem(), yv, c)
+	case yk == reflect.Interface && xk != reflect.Interface:
+		return equal(xv, yv.Elem(), c)
+	}
+
 	kind := xv.Type().Kind()
 
 	res.RelativeError = reflect.ValueOf(0.)
@@ -104,76 +198,218 @@ func equal(xv, yv reflect.Value, tol float64) (res EqualResult) {
 		return
 	}
 
+	// registered comparers and Equalers take precedence over the kind
+	if res, ok := equalCustom(xv, yv, c); ok {
+		return res
+	}
+
+	// pointers, maps and slices can form cycles, so a pair is considered
+	// equal if it is reached again while it is being compared
+	switch kind {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		var seen bool
+		if c, seen = c.visit(xv, yv); seen {
+			return
+		}
+		defer c.leave(xv, yv)
+	}
+
 	switch kind {
+	case reflect.Ptr, reflect.Interface:
+		if xv.IsNil() || yv.IsNil() {
+			res.Ok = xv.IsNil() && yv.IsNil()
+			return
+		}
+		// values of different types are not equal, as for reflect.DeepEqual
+		if res.Ok = xv.Elem().Type() == yv.Elem().Type(); !res.Ok {
+			return
+		}
+		if res = equal(xv.Elem(), yv.Elem(), c); !res.Ok {
+			return
+		}
+
 	case reflect.Slice, reflect.Array:
-		if res = equalSlice(xv, yv, tol); !res.Ok {
+		if res = equalSlice(xv, yv, c); !res.Ok {
 			return
 		}
 
 	case reflect.Map:
-		if res = equalMap(xv, yv, tol); !res.Ok {
+		if res = equalMap(xv, yv, c); !res.Ok {
 			return
 		}
 
 	case reflect.Struct:
-		if res = equalStruct(xv, yv, tol); !res.Ok {
+		if res = equalStruct(xv, yv, c); !res.Ok {
 			return
 		}
 
 	case reflect.Float32, reflect.Float64, // real-valued
 		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		x := xv.Convert(floatType).Interface().(float64)
-		y := yv.Convert(floatType).Interface().(float64)
-		if res = equalFloat(x, y, tol); !res.Ok {
+		if kind != reflect.Float32 && kind != reflect.Float64 && c.opaque {
+			// integers within opaque types, e.g. the clock of a time.Time,
+			// are not quantities within a tolerance, so must be identical
+			res.Ok = equalInt(xv, yv)
+			return
+		}
+		x, y := toFloat(xv), toFloat(yv)
+		if res = equalFloat(x, y, bitSize(xv.Type()), c); !res.Ok {
 			return
 		}
 	case reflect.Complex64, reflect.Complex128: // complex-valued
-		x := xv.Convert(complexType).Interface().(complex128)
-		y := yv.Convert(complexType).Interface().(complex128)
-		if res = equalComplex(x, y, tol); !res.Ok {
+		x, y := xv.Complex(), yv.Complex()
+		if res = equalComplex(x, y, xv.Type().Bits()/2, c); !res.Ok {
 			return
 		}
 
 	case reflect.Func:
-		if res = equalFunc(xv, yv, tol); !res.Ok {
+		if !xv.CanInterface() || !yv.CanInterface() {
+			// funcs held by unexported fields cannot be called, so must be identical
+			res.Ok = xv.Pointer() == yv.Pointer()
 			return
 		}
+		if res = equalFunc(xv, yv, c); !res.Ok {
+			return
+		}
+
+	case reflect.Bool:
+		res.Ok = xv.Bool() == yv.Bool()
+
+	case reflect.String:
+		res.Ok = xv.String() == yv.String()
+
+	default: // anything else: Chan, UnsafePointer
+		res.Ok = xv.Pointer() == yv.Pointer()
+	}
+	return
+}
 
-	default: // anything else: Bool, Chan, String, Interface, Ptr, UnsafePtr
-		if res.Ok = reflect.DeepEqual(xv.Interface(), yv.Interface()); !res.Ok {
+// visit is a pair of pointers, maps or slices being compared.
+type visit struct {
+	x, y uintptr
+	n    int // the length of slices, which can share a pointer
+	t    reflect.Type
+}
+
+// visitOf returns the visit for the values xv and yv,
+// and reports whether they are pointers, maps or slices.
+func visitOf(xv, yv reflect.Value) (v visit, ok bool) {
+	switch xv.Kind() {
+	case reflect.Slice:
+		v.n = xv.Len()
+		fallthrough
+	case reflect.Ptr, reflect.Map:
+		if xv.IsNil() || yv.IsNil() {
 			return
 		}
+		v.x, v.y, v.t, ok = xv.Pointer(), yv.Pointer(), xv.Type(), true
 	}
 	return
 }
 
+// visit records that the pointers, maps or slices xv and yv are being
+// compared, returning c with the visit recorded, and reports whether they
+// are already being compared. Any other values are not recorded.
+// A visit must be removed by leave when the comparison of xv and yv ends,
+// so that pairs are only considered equal while they are compared, and
+// not after they were found unequal, e.g. when a slice holds a pointer twice.
+func (c *config) visit(xv, yv reflect.Value) (*config, bool) {
+	v, ok := visitOf(xv, yv)
+	if !ok {
+		return c, false
+	}
+	if c.visited == nil {
+		cc := *c
+		cc.visited = make(map[visit]bool)
+		c = &cc
+	}
+	if c.visited[v] {
+		return c, true
+	}
+	c.visited[v] = true
+	return c, false
+}
+
+// leave removes the visit recorded for xv and yv, if any.
+func (c *config) leave(xv, yv reflect.Value) {
+	if v, ok := visitOf(xv, yv); ok && c.visited != nil {
+		delete(c.visited, v)
+	}
+}
+
+// opaque reports whether the struct type t has fields, none of which are
+// exported, e.g. time.Time, so that its values are only compared internally.
+func opaque(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return false
+		}
+	}
+	return t.NumField() > 0
+}
+
+// equalInt reports whether the integers xv and yv, of the same kind,
+// are identical. Unlike Interface, it can be used for values of unexported
+// struct fields.
+func equalInt(xv, yv reflect.Value) bool {
+	switch xv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return xv.Int() == yv.Int()
+	}
+	return xv.Uint() == yv.Uint()
+}
+
+// toFloat returns the real number held by v as a float64. Unlike Convert,
+// it can be used for values of unexported struct fields.
+func toFloat(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
 // equalSlice reports whether the slice xv is equal to the slice yv. It checks
 // the lengths are equal and the values for each index positiona are equal.
 // Numerical values must be equal within the specified tolerance.
-func equalSlice(xv, yv reflect.Value, tol float64) (res EqualResult) {
+func equalSlice(xv, yv reflect.Value, c *config) (res EqualResult) {
 	// check the slices have equal lengths
 	n := xv.Len()
 	if res.Ok = (n == yv.Len()); !res.Ok {
 		res.LengthMismatch = true
 		return
 	}
+	if c.isUnordered() {
+		return equalUnordered(xv, yv, c)
+	}
 	// check that the items at each position are equal
+	fail := EqualResult{Ok: true}
 	for i := 0; i < n; i++ {
-		if res = equal(xv.Index(i), yv.Index(i), tol); !res.Ok {
+		if res = equalAt(step{index: i}, xv.Index(i), yv.Index(i), c); !res.Ok {
 			res.Position = i
-			return
+			res.MissingValue = false // nothing is missing from xv itself
+			if fail.Ok {
+				fail = res
+			}
+			if !more(c) {
+				break
+			}
 		}
 	}
+	if !fail.Ok {
+		res = fail
+	}
 	return
 }
 
 // equalMap reports whether the map xn is equal to the map yv
 // for every key, and that they identical keys. Numerical values
 // must be equal within the specified tolerance.
-func equalMap(xv, yv reflect.Value, tol float64) (res EqualResult) {
-	xkeys := xv.MapKeys()
-	ykeys := yv.MapKeys()
+func equalMap(xv, yv reflect.Value, c *config) (res EqualResult) {
+	xkeys := c.mapKeys(xv)
+	ykeys := c.mapKeys(yv)
 
 	// check that x and y have the same number of keys
 	n := len(ykeys)
@@ -181,72 +417,207 @@ func equalMap(xv, yv reflect.Value, tol float64) (res EqualResult) {
 		res.LengthMismatch = true
 		return
 	}
+	if c.cmp != nil { // collect the mismatches in a consistent order
+		sort.Slice(ykeys, func(i, j int) bool {
+			return fmt.Sprint(ykeys[i]) < fmt.Sprint(ykeys[j])
+		})
+	}
 
 	// check that each key in xkeys is in ykeys. Need to iterate over all xkeys
 	// for each ykey since ordering of keys from maps is non-deterministic
+	fail := EqualResult{Ok: true}
 	for i := 0; i < n; i++ {
 		ykey := ykeys[i]
 		for _, xkey := range xkeys {
-			if res = equal(xkey, ykey, tol); res.Ok {
+			if res = equal(xkey, ykey, quiet(c)); res.Ok {
 				break
 			}
 		}
-		// if ykey was not found, return false
-		if !res.Ok {
+		if !res.Ok { // ykey was not found
 			res.Position = i
 			res.MissingValue = true
-			return
-		}
-		// if the items for this key are not equal, return false
-		if res = equal(xv.MapIndex(ykey), yv.MapIndex(ykey), tol); !res.Ok {
+			missing(step{key: ykey}, yv.MapIndex(ykey), c)
+		} else if res = equalAt(step{key: ykey}, xv.MapIndex(ykey), yv.MapIndex(ykey), c); !res.Ok {
 			res.Position = i
-			return
+			res.MissingValue = false // nothing is missing from xv itself
 		}
+		if !res.Ok {
+			if fail.Ok {
+				fail = res
+			}
+			if !more(c) {
+				break
+			}
+		}
+	}
+	if !fail.Ok {
+		res = fail
 	}
 	return
 }
 
 // equalStruct reports whether the struct xn is equal to the struct yv
 // for every field, and that they identical fields. Numerical values
-// must be equal within the specified tolerance.
-func equalStruct(xv, yv reflect.Value, tol float64) (res EqualResult) {
+// must be equal within the specified tolerance, unless overridden
+// by the testutil tag of the field in yv (see fieldTag).
+func equalStruct(xv, yv reflect.Value, c *config) (res EqualResult) {
 	// check that x and y have the same number of fields
 	n := xv.Type().NumField()
 	if res.Ok = (n == yv.Type().NumField()); !res.Ok {
 		res.LengthMismatch = true
 		return
 	}
+	tags, err := fieldTagsOf(yv.Type())
+	if err != nil {
+		panic(tagError{err})
+	}
+	if !c.opaque && opaque(yv.Type()) {
+		cc := *c
+		cc.opaque = true
+		c = &cc
+	}
 	// check that the fields at each position are equal
+	fail := EqualResult{Ok: true}
 	for i := 0; i < n; i++ {
-		if res.Ok = xv.Type().Field(i).Name == yv.Type().Field(i).Name; !res.Ok {
+		f := yv.Type().Field(i)
+		name := f.Name
+		if res.Ok = xv.Type().Field(i).Name == name; !res.Ok {
 			res.MissingValue = true
 			res.Position = i
+			missing(step{field: name}, yv.Field(i), c)
 			return
 		}
-		if res = equal(xv.Field(i), yv.Field(i), tol); !res.Ok {
+		fc := c
+		switch tag := tags[i]; {
+		case tag.ignore, c.ignoredField(f):
+			continue
+		case tag.hasTol:
+			fc = c.withTolerance(tag.tol)
+		}
+		if res = equalAt(step{field: name}, xv.Field(i), yv.Field(i), fc); !res.Ok {
 			res.Position = i
-			return
+			res.MissingValue = false // nothing is missing from xv itself
+			if fail.Ok {
+				fail = res
+			}
+			if !more(c) {
+				break
+			}
 		}
 	}
+	if !fail.Ok {
+		res = fail
+	}
 	return
 }
 
-// equalFloat reports whether x equals y within the specified tolerance.
-// Zeros and Infinities are considered equal if they have the same sign.
-// NaNs are always considered equal to other NaNs.
-func equalFloat(x, y, tol float64) (res EqualResult) {
+// NaNPolicy determines whether NaNs are equal to each other.
+type NaNPolicy int
+
+const (
+	// NaNEqual considers any NaN equal to any other NaN. This is the default.
+	NaNEqual NaNPolicy = iota
+
+	// NaNUnequal considers NaN unequal to everything, including NaN,
+	// as for the == operator.
+	NaNUnequal
+
+	// NaNPayload considers NaNs equal only if they have identical bits,
+	// i.e. the same sign and payload.
+	NaNPayload
+)
+
+// ZeroPolicy determines whether zeros of opposite sign are equal.
+type ZeroPolicy int
+
+const (
+	// ZeroSigned considers +0 and -0 unequal. This is the default.
+	ZeroSigned ZeroPolicy = iota
+
+	// ZeroUnsigned considers +0 and -0 equal, as for the == operator.
+	ZeroUnsigned
+)
+
+// ComplexPolicy determines how the tolerance applies to complex numbers.
+type ComplexPolicy int
+
+const (
+	// ComplexParts requires the real and imaginary parts to separately be
+	// equal within the tolerance. This is the default.
+	ComplexParts ComplexPolicy = iota
+
+	// ComplexModulus requires the modulus of the difference to be within the
+	// tolerance, i.e. |x - y| ≤ Rel * |y| for a relative tolerance, so that
+	// a part which is negligible relative to |y| may have any relative error.
+	// ULPs are counted as |x - y| in units in the last place of |y|.
+	// NaNs, infinities and signed zeros are still compared by parts.
+	ComplexModulus
+)
+
+// RelativePolicy determines how the relative error between an actual value x
+// and an expected value y is defined, both for the Rel bound of a tolerance
+// and for the RelativeError reported in an EqualResult.
+type RelativePolicy int
+
+const (
+	// RelativeExpected defines the relative error as (x - y)/y, i.e.
+	// relative to the expected value. This is the default.
+	RelativeExpected RelativePolicy = iota
+
+	// RelativeMax defines the relative error as (x - y)/max(|x|, |y|),
+	// which is symmetric in x and y, e.g. when comparing two implementations.
+	RelativeMax
+
+	// RelativeMean defines the relative error as (x - y)/((|x| + |y|)/2),
+	// which is also symmetric in x and y.
+	RelativeMean
+
+	// RelativeLog defines the relative error as the log-ratio log(x/y), which
+	// is antisymmetric in x and y and is infinite if their signs differ or
+	// either is zero. The number of significant digits to which x and y agree
+	// is -log10(|log(x/y)|). For complex numbers compared by ComplexModulus,
+	// it is log(1 + |x - y|/|y|).
+	RelativeLog
+)
+
+// equalNaN reports whether the NaNs x and y are equal under the policy p.
+func equalNaN(x, y float64, p NaNPolicy) bool {
+	switch p {
+	case NaNUnequal:
+		return false
+	case NaNPayload:
+		return math.Float64bits(x) == math.Float64bits(y)
+	}
+	return true
+}
+
+// equalFloat reports whether x equals y within the tolerance in c.
+// Zeros are considered equal if they have the same sign, or regardless of
+// sign if c has the ZeroUnsigned policy. An expected infinity is only equal
+// to the same infinity, not to any finite number within the tolerance.
+// NaNs are considered equal to other NaNs according to the NaNPolicy in c.
+//
+// bits is the size of the floating-point type of x and y, used to count ULPs,
+// or 0 if they are integers.
+func equalFloat(x, y float64, bits int, c *config) (res EqualResult) {
 	diff := x - y
 	res.Numerical = true
 	res.AbsoluteError = reflect.ValueOf(diff)
+	res.RelativeError = res.AbsoluteError
+	res.ULPError = ulps(x, y, bits)
 
-	if math.IsNaN(x) && math.IsNaN(y) {
-		res.Ok = true
-		res.RelativeError = reflect.ValueOf(0.)
+	// a NaN only equals another NaN, and never an infinity
+	if math.IsNaN(x) || math.IsNaN(y) {
+		if res.Ok = math.IsNaN(x) && math.IsNaN(y) && equalNaN(x, y, c.nans); res.Ok {
+			res.RelativeError = reflect.ValueOf(0.)
+			res.ULPError = 0
+		}
 		return
 	}
 
+	// an infinite y only equals the same infinity, whatever the tolerance
 	if x == y || math.IsInf(y, 0) {
-		res.Ok = math.Signbit(x) == math.Signbit(y)
+		res.Ok = x == y && (math.Signbit(x) == math.Signbit(y) || c.zeros == ZeroUnsigned)
 		if !res.Ok && math.IsInf(y, 0) {
 			res.AbsoluteError = reflect.ValueOf(y)
 			res.RelativeError = reflect.ValueOf(y)
@@ -254,29 +625,24 @@ func equalFloat(x, y, tol float64) (res EqualResult) {
 		if res.Ok {
 			res.AbsoluteError = reflect.ValueOf(0.)
 			res.RelativeError = reflect.ValueOf(0.)
+			res.ULPError = 0
 		}
 		return
 	}
 
-	// check magnitude of relative error, i.e. |expected - actual| / |actual| < |tol|
-	if y == 0 {
-		maxdiff := tol
-		res.Ok = math.Abs(diff) < math.Abs(maxdiff)
-		res.RelativeError = reflect.ValueOf(diff)
-		return
-	}
+	res.RelativeError = reflect.ValueOf(relativeError(x, y, diff, c.relative))
 
-	maxdiff := y * tol
-	res.Ok = math.Abs(diff) <= math.Abs(maxdiff)
-	res.RelativeError = reflect.ValueOf(diff / y)
+	// y is finite and differs from x at this point
+	res.Ok = c.tol.within(x, y, diff, res.ULPError, c.relative)
 	return
 }
 
-// equalComplex reports whether x equals y within the specified tolerance
-// for both the real and imaginary parts.
-func equalComplex(x, y complex128, tol float64) (res EqualResult) {
-	rr := equalFloat(real(x), real(y), tol)
-	ir := equalFloat(imag(x), imag(y), tol)
+// equalComplex reports whether x equals y within the tolerance in c
+// for both the real and imaginary parts, which have the given bit size,
+// or for the modulus of their difference if c has the ComplexModulus policy.
+func equalComplex(x, y complex128, bits int, c *config) (res EqualResult) {
+	rr := equalFloat(real(x), real(y), bits, c)
+	ir := equalFloat(imag(x), imag(y), bits, c)
 	relerr := complex(
 		rr.RelativeError.Interface().(float64),
 		ir.RelativeError.Interface().(float64),
@@ -289,19 +655,46 @@ func equalComplex(x, y complex128, tol float64) (res EqualResult) {
 	res.Ok = rr.Ok && ir.Ok
 	res.RelativeError = reflect.ValueOf(relerr)
 	res.AbsoluteError = reflect.ValueOf(abserr)
+	res.ULPError = rr.ULPError
+	if ir.ULPError > res.ULPError {
+		res.ULPError = ir.ULPError
+	}
+
+	if c.complexes == ComplexModulus && x != y && finite(x) && finite(y) {
+		diff, mod := cmplx.Abs(x-y), cmplx.Abs(y)
+		xmod := cmplx.Abs(x)
+		if c.relative == RelativeLog { // log(1 + |x - y|/|y|), ignoring the phase
+			xmod = mod + diff
+		}
+		res.ULPError = ulpsOf(diff, mod, bits)
+		res.Ok = c.tol.within(xmod, mod, diff, res.ULPError, c.relative)
+	}
 	return
 }
 
+// finite reports whether neither part of z is NaN or infinite.
+func finite(z complex128) bool {
+	return !cmplx.IsNaN(z) && !cmplx.IsInf(z)
+}
+
 // equalFunc reports whether two functions xv and xy are equivalent by
 // comparing their respective outputs on randomly generated inputs.
 // Numerical output values must be equal within the specified tolerance.
-func equalFunc(xv, yv reflect.Value, tol float64) (res EqualResult) {
-	r := rand.New(rand.NewSource(time.Now().Unix()))
+// The inputs are generated from the seed in c, or from the time if c is unseeded.
+func equalFunc(xv, yv reflect.Value, c *config) (res EqualResult) {
+	seed := time.Now().Unix()
+	if c.seeded {
+		seed = c.seed
+	}
+	r := rand.New(rand.NewSource(seed))
 
 	// if checking for exact equality just use the testing/quick package
-	if tol == 0 {
+	if c.tol.isZero() {
 		err := quick.CheckEqual(xv.Interface(), yv.Interface(), &quick.Config{Rand: r})
 		res.Ok = (err == nil)
+		if err, ok := err.(*quick.CheckEqualError); ok {
+			res.Counterexample = append(err.In, err.Out2...)
+		}
 		return
 	}
 
@@ -315,7 +708,8 @@ func equalFunc(xv, yv reflect.Value, tol float64) (res EqualResult) {
 		xcall := xv.Call(args)
 		ycall := yv.Call(args)
 		for i := 0; i < len(xcall); i++ {
-			if res = equal(xcall[i], ycall[i], tol); !res.Ok {
+			if res = equal(xcall[i], ycall[i], quiet(c)); !res.Ok {
+				res.Counterexample = interfaces(append(args, ycall...))
 				return
 			}
 		}
@@ -339,19 +733,11 @@ func mockArgs(fv reflect.Value, r *rand.Rand) (args []reflect.Value, err error)
 	return
 }
 
-// validateTolerance ensures the tolerance passed is sensibly valued.
-func validateTolerance(tolerance interface{}) (tol float64) {
-	t := reflect.ValueOf(tolerance)
-	switch kind := t.Kind(); kind {
-	case reflect.Float32, reflect.Float64,
-		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		tol = math.Abs(t.Convert(floatType).Interface().(float64))
-	case reflect.Complex64, reflect.Complex128:
-		tol = cmplx.Abs(t.Convert(complexType).Interface().(complex128))
-	}
-	if math.IsNaN(tol) {
-		tol = 0
+// interfaces returns the values held by vs.
+func interfaces(vs []reflect.Value) []interface{} {
+	is := make([]interface{}, len(vs))
+	for i, v := range vs {
+		is[i] = valueOf(v)
 	}
-	return
+	return is
 }
@@ -5,8 +5,11 @@
 package testutil_test
 
 import (
+	"fmt"
 	"math"
+	"math/big"
 	"testing"
+	"time"
 
 	. "github.com/scientificgo/testutil"
 )
@@ -18,6 +21,9 @@ var (
 	cinf = complex(inf, inf)
 )
 
+// Equal keeps its signature, so that it can be stored in a func variable.
+var _ func(x, y, tolerance interface{}) EqualResult = Equal
+
 func TestEqual(t *testing.T) {
 	type mystruct struct {
 		Int    int
@@ -88,3 +94,230 @@ func TestEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestEqual_Counterexample(t *testing.T) {
+	cases := []struct {
+		Label         string
+		In1, In2, In3 interface{}
+		Out           bool
+	}{
+		{"Exact", math.Sin, math.Cos, 0, true},
+		{"Tolerance", math.Sin, math.Cos, 1e-9, true},
+		{"Equal", math.Sin, math.Sin, 1e-9, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			res := Equal(c.In1, c.In2, c.In3)
+			if found := res.Counterexample != nil; found != c.Out {
+				t.Errorf("Error: wanted counterexample %v, got %v", c.Out, res.Counterexample)
+			}
+			if res.Counterexample == nil {
+				return
+			}
+			// the counterexample holds the input and the output of the reference
+			x := res.Counterexample[0].(float64)
+			if y := res.Counterexample[1].(float64); y != math.Cos(x) {
+				t.Errorf("Error: wanted output %v, got %v", math.Cos(x), y)
+			}
+		})
+	}
+}
+
+func TestEqual_Policies(t *testing.T) {
+	payload := math.Float64frombits(math.Float64bits(nan) | 2)
+	negzero := math.Copysign(0, -1)
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		In3      Option
+		Out      bool
+	}{
+		{"NaNEqual", nan, payload, WithNaNPolicy(NaNEqual), true},
+		{"NaNEqual", cnan, cnan, WithNaNPolicy(NaNEqual), true},
+		{"NaNUnequal", nan, nan, WithNaNPolicy(NaNUnequal), false},
+		{"NaNUnequal", []float64{1, nan}, []float64{1, nan}, WithNaNPolicy(NaNUnequal), false},
+		{"NaNUnequal", complex(1, nan), complex(1, nan), WithNaNPolicy(NaNUnequal), false},
+		{"NaNPayload", nan, nan, WithNaNPolicy(NaNPayload), true},
+		{"NaNPayload", nan, payload, WithNaNPolicy(NaNPayload), false},
+		{"NaNPayload", float32(nan), float32(nan), WithNaNPolicy(NaNPayload), true},
+		{"NaNInf", nan, inf, WithNaNPolicy(NaNEqual), false},
+		{"NaNInf", nan, -inf, WithNaNPolicy(NaNUnequal), false},
+		{"NaNInf", nan, inf, WithNaNPolicy(NaNPayload), false},
+		{"NaNInf", inf, nan, WithNaNPolicy(NaNEqual), false},
+		{"NaNInf", complex(nan, 0), complex(inf, 0), WithComplexPolicy(ComplexModulus), false},
+		{"ZeroSigned", negzero, 0., WithZeroPolicy(ZeroSigned), false},
+		{"ZeroSigned", complex(0, negzero), 0i, WithZeroPolicy(ZeroSigned), false},
+		{"ZeroUnsigned", negzero, 0., WithZeroPolicy(ZeroUnsigned), true},
+		{"ZeroUnsigned", complex(0, negzero), 0i, WithZeroPolicy(ZeroUnsigned), true},
+		{"ZeroUnsigned", -inf, inf, WithZeroPolicy(ZeroUnsigned), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := EqualWith(c.In1, c.In2, WithTolerance(0.1), c.In3); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestTestWith_Policies(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out float64
+	}{
+		{"", math.Copysign(0, -1), 0},
+		{"", 0, 0},
+	}
+	neg := func(x float64) float64 { return -x }
+	TestWith(t, cases, []Func{neg}, WithZeroPolicy(ZeroUnsigned))
+
+	var errs []string
+	TestWith(t, cases, []Func{neg}, WithReporter(recorder(&errs)))
+	if len(errs) != 1 {
+		t.Errorf("Error: wanted 1 failure, got %q", errs)
+	}
+}
+
+func TestEqual_ComplexModulus(t *testing.T) {
+	modulus := WithComplexPolicy(ComplexModulus)
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		In3      interface{}
+		In4      Option
+		Out      bool
+	}{
+		{"Parts", 1e20 + 1e-5i, 1e20 + 0i, 1e-12, WithComplexPolicy(ComplexParts), false},
+		{"Modulus", 1e20 + 1e-5i, 1e20 + 0i, 1e-12, modulus, true},
+		{"Modulus", 1 + 1e-11i, 1 + 0i, 1e-12, modulus, false},
+		{"Modulus", 3 + 4.000001i, 3 + 4i, 1e-6, modulus, true},
+		{"Modulus", complex64(3 + 4.01i), complex64(3 + 4i), 1e-3, modulus, false},
+		{"Abs", 1e-10i, 0i, Tolerance{Abs: 1e-9}, modulus, true},
+		{"ULP", complex(1, 1e-17), 1 + 0i, ULP(1), modulus, true},
+		{"ULP", complex(1, 1e-15), 1 + 0i, ULP(1), modulus, false},
+		{"ULP", complex64(complex(1, 1e-8)), complex64(1), ULP(1), modulus, true},
+		{"NaN", cnan, cnan, 1e-3, modulus, true},
+		{"Inf", complex(inf, 1), complex(inf, 0), 1e-3, modulus, false},
+		{"Zero", complex(math.Copysign(0, -1), 0), 0i, 1e-3, modulus, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := EqualWith(c.In1, c.In2, WithTolerance(c.In3), c.In4); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestEqual_Pointers(t *testing.T) {
+	type node struct {
+		Value float64
+		Next  *node
+	}
+	ring := func(xs ...float64) *node {
+		first := &node{Value: xs[0]}
+		n := first
+		for _, x := range xs[1:] {
+			n.Next = &node{Value: x}
+			n = n.Next
+		}
+		n.Next = first
+		return first
+	}
+	one, two := 1., 2.
+
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		Out      bool
+	}{
+		{"Pointer", &one, &two, false},
+		{"Pointer", &one, &one, true},
+		{"Pointer", &two, &[]float64{2 + 1e-12}[0], true},
+		{"Nil", (*float64)(nil), (*float64)(nil), true},
+		{"Nil", &one, (*float64)(nil), false},
+		{"Struct", &node{Value: 1}, &node{Value: 1 + 1e-12}, true},
+		{"Interfaces", []interface{}{1., "a", nil}, []interface{}{1 + 1e-12, "a", nil}, true},
+		{"Interfaces", []interface{}{1., "a"}, []interface{}{1.1, "a"}, false},
+		{"Interfaces", []interface{}{1}, []interface{}{1.}, false},
+		{"Interfaces", map[string]interface{}{"x": []float64{1}}, map[string]interface{}{"x": []float64{1 + 1e-12}}, true},
+		{"Cycle", ring(1, 2, 3), ring(1, 2, 3+1e-12), true},
+		{"Cycle", ring(1, 2, 3), ring(1, 2, 4), false},
+		{"Cycle", ring(1, 2), ring(1, 2, 1, 2), true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := Equal(c.In1, c.In2, 1e-9); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+type interval struct {
+	lo, hi float64
+	name   string
+	open   bool
+	f      func(float64) float64
+}
+
+type solverStats struct {
+	Residual float64
+	iters    int
+}
+
+func TestEqual_Unexported(t *testing.T) {
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		In3      bool
+		Out      bool
+	}{
+		{"Equal", interval{0, 1, "a", true, nil}, interval{0, 1 + 1e-12, "a", true, nil}, true, true},
+		{"Float", interval{0, 1, "a", true, nil}, interval{0, 1.1, "a", true, nil}, true, false},
+		{"String", interval{0, 1, "a", true, nil}, interval{0, 1, "b", true, nil}, true, false},
+		{"Bool", interval{0, 1, "a", true, nil}, interval{0, 1, "a", false, nil}, true, false},
+		{"Func", interval{f: math.Sin}, interval{f: math.Sin}, true, true},
+		{"Func", interval{f: math.Sin}, interval{f: math.Cos}, true, false},
+		{"Nested", []*interval{{lo: 1}}, []*interval{{lo: 1 + 1e-12}}, true, true},
+		{"Skipped", interval{0, 1, "a", true, nil}, interval{2, 3, "b", false, math.Sin}, false, true},
+		{"Error", fmt.Errorf("x"), fmt.Errorf("x"), true, true},
+		{"Error", fmt.Errorf("x"), fmt.Errorf("y"), true, false},
+		// the internals of opaque types are compared exactly
+		{"Time", time.Unix(1e9, 0), time.Unix(1e9, 0), true, true},
+		{"Time", time.Unix(1e9, 0), time.Unix(1e9+1, 0), true, false},
+		{"Int", big.NewInt(1e15), big.NewInt(1e15 + 1), true, false},
+		// other unexported integers are compared within the tolerance
+		{"Iterations", solverStats{1, 1e12}, solverStats{1, 1e12 + 1}, true, true},
+		{"Iterations", solverStats{1, 1e12}, solverStats{1, 2e12}, true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := EqualWith(c.In1, c.In2, WithTolerance(1e-9), WithUnexported(c.In3)); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestTestWith_Unexported(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    float64
+		Out   interval
+	}{
+		{"", 2, interval{lo: 1, hi: 2}},
+	}
+	f := func(x float64) interval { return interval{lo: 1, hi: x + 1} }
+
+	var errs []string
+	TestWith(t, cases, []Func{f}, WithReporter(recorder(&errs)))
+	if want := []string{"[0].hi: Got 3, want 2 (δ=0.5)"}; !Equal(errs, want, nil).Ok {
+		t.Errorf("Error: wanted %q, got %q", want, errs)
+	}
+}
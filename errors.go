@@ -0,0 +1,169 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ErrorMatch represents a way of matching an actual error to an expected error.
+type ErrorMatch int
+
+// The ways of matching an actual error, err, to an expected error, target.
+const (
+	MatchIs       ErrorMatch = iota + 1 // errors.Is(err, target)
+	MatchAs                             // errors.As(err, &v) for a v of the type of target
+	MatchMessage                        // err.Error() == target.Error()
+	MatchContains                       // err.Error() contains target.Error()
+	MatchRegexp                         // err.Error() matches the regular expression target.Error()
+)
+
+// errorMatcher is an expected error which matches
+// actual errors according to match.
+type errorMatcher struct {
+	match  ErrorMatch
+	target error
+	typ    reflect.Type   // for MatchAs
+	re     *regexp.Regexp // for MatchRegexp
+}
+
+// ErrorIs returns an expected error for use in cases, which
+// matches an actual error err if errors.Is(err, target).
+func ErrorIs(target error) error {
+	return newErrorMatcher(MatchIs, target, nil)
+}
+
+// ErrorAs returns an expected error for use in cases, which matches an
+// actual error err if errors.As(err, target). As for errors.As, target
+// must be a non-nil pointer to a type implementing error, or to an interface.
+// ErrorAs panics if target is not such a pointer.
+func ErrorAs(target interface{}) error {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr || reflect.ValueOf(target).IsNil() {
+		panic("testutil: ErrorAs target must be a non-nil pointer")
+	}
+	if e := t.Elem(); e.Kind() != reflect.Interface && !e.Implements(errorType) {
+		panic(fmt.Sprintf("testutil: ErrorAs target %v must be a pointer to an interface or a type implementing error", t))
+	}
+	return newErrorMatcher(MatchAs, nil, t.Elem())
+}
+
+// ErrorContains returns an expected error for use in cases, which
+// matches an actual error if its message contains substr.
+func ErrorContains(substr string) error {
+	return newErrorMatcher(MatchContains, errors.New(substr), nil)
+}
+
+// ErrorMatches returns an expected error for use in cases, which matches an
+// actual error if its message matches the regular expression pattern.
+// It panics if pattern is not a valid regular expression.
+func ErrorMatches(pattern string) error {
+	return newErrorMatcher(MatchRegexp, errors.New(pattern), nil)
+}
+
+// newErrorMatcher returns an errorMatcher matching target with match.
+// For MatchAs, typ is the type to match, or nil for the type of target.
+func newErrorMatcher(match ErrorMatch, target error, typ reflect.Type) *errorMatcher {
+	m := &errorMatcher{match: match, target: target, typ: typ}
+	switch match {
+	case MatchAs:
+		if typ == nil {
+			m.typ = reflect.TypeOf(target)
+		}
+	case MatchRegexp:
+		m.re = regexp.MustCompile(target.Error())
+	}
+	return m
+}
+
+// Error describes the errors matched by m.
+func (m *errorMatcher) Error() string {
+	switch m.match {
+	case MatchIs:
+		return fmt.Sprintf("error matching %q", m.target)
+	case MatchAs:
+		return fmt.Sprintf("error as %v", m.typ)
+	case MatchMessage:
+		return fmt.Sprintf("error with message %q", m.target)
+	case MatchContains:
+		return fmt.Sprintf("error containing %q", m.target)
+	default:
+		return fmt.Sprintf("error matching regexp %q", m.re)
+	}
+}
+
+// matches reports whether err matches m.
+func (m *errorMatcher) matches(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch m.match {
+	case MatchIs:
+		return errors.Is(err, m.target)
+	case MatchAs:
+		return errors.As(err, reflect.New(m.typ).Interface())
+	case MatchMessage:
+		return err.Error() == m.target.Error()
+	case MatchContains:
+		return strings.Contains(err.Error(), m.target.Error())
+	default:
+		return m.re.MatchString(err.Error())
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+var errorMatcherType = reflect.TypeOf((*errorMatcher)(nil))
+
+// errorMatcherOf returns the errorMatcher held by yv, if any. The type
+// of yv is checked first, as yv is usually not an error, so that other
+// values are not copied by Interface.
+func errorMatcherOf(yv reflect.Value) (m *errorMatcher, ok bool) {
+	if !yv.IsValid() || !yv.CanInterface() {
+		return
+	}
+	switch yv.Kind() {
+	case reflect.Ptr:
+		if yv.Type() != errorMatcherType {
+			return
+		}
+	case reflect.Interface:
+		if yv.IsNil() || yv.Elem().Type() != errorMatcherType {
+			return
+		}
+	default:
+		return
+	}
+	m, ok = yv.Interface().(*errorMatcher)
+	return
+}
+
+// equalError reports whether the value xv is an error matching m.
+func equalError(xv reflect.Value, m *errorMatcher) (res EqualResult) {
+	res.RelativeError = reflect.ValueOf(0.)
+	res.AbsoluteError = reflect.ValueOf(0.)
+	if xv.IsValid() && xv.Type().Implements(errorType) && xv.CanInterface() {
+		err, _ := xv.Interface().(error)
+		res.Ok = m.matches(err)
+	}
+	return
+}
+
+// matchErrors returns yv as an errorMatcher using match if it
+// holds a non-nil error, or yv itself otherwise.
+func matchErrors(yv reflect.Value, match ErrorMatch) reflect.Value {
+	if !yv.IsValid() || !yv.CanInterface() {
+		return yv
+	}
+	target, ok := yv.Interface().(error)
+	if _, isMatcher := target.(*errorMatcher); !ok || target == nil || isMatcher {
+		return yv
+	}
+	return reflect.ValueOf(newErrorMatcher(match, target, nil))
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func TestTest_ErrorMatchers(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    string
+		Out1  int
+		Out2  error
+	}{
+		{"", "1", 1, nil},
+		{"", "x", 0, ErrorIs(strconv.ErrSyntax)},
+		{"", "99999999999999999999", 9223372036854775807, ErrorAs(new(*strconv.NumError))},
+		{"", "", 0, ErrorContains("invalid syntax")},
+		{"", "1e3", 0, ErrorMatches(`^strconv\.Atoi: parsing "1e3": .* syntax$`)},
+	}
+	Test(t, nil, cases, strconv.Atoi)
+}
+
+func TestTest_ErrorMatch(t *testing.T) {
+	open := func(name string) error {
+		_, err := os.Open(name)
+		return err
+	}
+	wrap := func(err error) error {
+		if err == nil {
+			return nil
+		}
+		return fmt.Errorf("wrapped: %w", err)
+	}
+	wrapped := func(name string) error { return wrap(open(name)) }
+
+	cases := []struct {
+		Label string
+		In    string
+		Out   error
+	}{
+		{"", "/does/not/exist", os.ErrNotExist},
+	}
+	TestWith(t, cases, []Func{open}, WithErrorMatch(0, MatchIs))
+	TestWith(t, cases, []Func{wrapped}, WithErrorMatch(0, MatchIs))
+	TestWith(t, cases, []Func{wrapped, open}, WithErrorMatch(0, MatchContains))
+
+	messages := []struct {
+		Label string
+		In    string
+		Out   error
+	}{
+		{"", "/does/not/exist", errors.New("open /does/not/exist: no such file or directory")},
+		{"", "/does/not/exist", errors.New("no such file")},
+		{"", "/does/not/exist", errors.New("^open .*: no such")},
+		{"", "/does/not/exist", &os.PathError{}},
+	}
+	TestWith(t, messages[:1], []Func{open}, WithErrorMatch(0, MatchMessage))
+	TestWith(t, messages[1:2], []Func{wrapped}, WithErrorMatch(0, MatchContains))
+	TestWith(t, messages[2:3], []Func{open}, WithErrorMatch(0, MatchRegexp))
+	TestWith(t, messages[3:], []Func{wrapped}, WithErrorMatch(0, MatchAs))
+}
+
+func TestCheck_ErrorMatchers(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    string
+		Out1  int
+		Out2  error
+	}{
+		{"", "1", 1, ErrorContains("invalid")},
+		{"", "x", 0, ErrorIs(os.ErrNotExist)},
+		{"", "x", 0, ErrorAs(new(*os.PathError))},
+		{"", "x", 0, strconv.ErrSyntax},
+	}
+	rep, err := Check(nil, cases, strconv.Atoi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`[1]: Got <nil>, want error containing "invalid"`,
+		`[1]: Got strconv.Atoi: parsing "x": invalid syntax, want error matching "file does not exist"`,
+		fmt.Sprintf(`[1]: Got strconv.Atoi: parsing "x": invalid syntax, want error as %T`, &os.PathError{}),
+		`[1]: Got strconv.Atoi: parsing "x": invalid syntax, want invalid syntax`,
+	}
+	got := []string{}
+	for _, r := range rep.Failures() {
+		got = append(got, r.Err.Error())
+	}
+	if !Equal(got, want, nil).Ok {
+		t.Errorf("Error: wanted %q, got %q", want, got)
+	}
+}
+
+func TestErrorAs_Invalid(t *testing.T) {
+	for _, target := range []interface{}{nil, os.PathError{}, (*os.PathError)(nil), new(int)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Error: wanted panic for target %T", target)
+				}
+			}()
+			ErrorAs(target)
+		}()
+	}
+}
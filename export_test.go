@@ -8,3 +8,5 @@ var (
 	ParseFuncs = parseFuncs
 	ParseCases = parseCases
 )
+
+var CaseLiteral = caseLiteral
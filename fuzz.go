@@ -0,0 +1,112 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package testutil
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Fuzz is a generic case-driven fuzzing function that accepts a slice of
+// cases, a numerical tolerance and either 1 or 2 functions to be fuzzed.
+// The inputs of every case are added to the seed corpus of f, so the same
+// table can be used for testing and fuzzing. Outputs provided in the cases
+// are optional and are ignored.
+//
+// If 1 function is provided, then it is treated as a property and must
+// return a single bool, which must be true for every fuzzed input.
+//
+// If 2 functions are provided, then their respective outputs are compared
+// for every fuzzed input, and must be equal within the numerical tolerance.
+//
+// The inputs of the functions must be of types supported by testing.F.
+func Fuzz(f *testing.F, tolerance interface{}, cases Cases, funcs ...Func) {
+	c := newConfig(WithTolerance(tolerance))
+	cvs, nc, nfc, err := parseCases(cases)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f1v, f2v, err := parseFuncs(funcs...)
+	if err != nil {
+		f.Fatal(err)
+	}
+	ft := f1v.Type()
+	nIn := ft.NumIn()
+	nOut := ft.NumOut()
+
+	if f2v.IsNil() && (nOut != 1 || ft.Out(0).Kind() != reflect.Bool) {
+		f.Fatalf("wrong output type for property. Got %v, want %v", ft, "bool")
+	}
+	if nfc-1 != nIn+nOut && nfc-1 != nIn { // outputs are optional
+		f.Fatalf("wrong number of input slices. Got %v, want %v", nfc-1, nIn)
+	}
+
+	// seed the corpus with the inputs of every case
+	for i := 0; i < nc; i++ {
+		in, err := seedFrom(cvs.Index(i), ft)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(in...)
+	}
+
+	// the fuzz target must have the signature func(*testing.T, In1, ..., InN)
+	types := make([]reflect.Type, nIn+1)
+	types[0] = reflect.TypeOf((*testing.T)(nil))
+	for i := 0; i < nIn; i++ {
+		types[i+1] = ft.In(i)
+	}
+	target := reflect.MakeFunc(reflect.FuncOf(types, nil, false), func(args []reflect.Value) []reflect.Value {
+		t := args[0].Interface().(*testing.T)
+		fuzztest(t, args[1:], f1v, f2v, nOut, c)
+		return nil
+	})
+	f.Fuzz(target.Interface())
+}
+
+// fuzztest checks a single fuzzed input in.
+func fuzztest(t *testing.T, in []reflect.Value, f1v, f2v reflect.Value, nOut int, c *config) {
+	res := f1v.Call(in)
+	if f2v.IsNil() {
+		if !res[0].Bool() {
+			t.Errorf("property does not hold\n\tcounterexample: %v", caseLiteral("fuzz", interfaces(in)))
+		}
+		return
+	}
+	out := f2v.Call(in)
+	failed := false
+	for i := 0; i < nOut; i++ {
+		if err := compareOutput(i, res[i], out[i], outputConfig(in, out[i], c)).Err; err != nil {
+			t.Error(err)
+			failed = true
+		}
+	}
+	if failed {
+		t.Logf("counterexample: %v", caseLiteral("fuzz", interfaces(append(in, out...))))
+	}
+}
+
+// seedFrom creates a seed corpus entry from the inputs of the case cv,
+// converted to the input types of the function type ft.
+func seedFrom(cv reflect.Value, ft reflect.Type) (in []interface{}, err error) {
+	vs := sliceFrom(cv, 1, ft.NumIn())
+	in = make([]interface{}, len(vs))
+	for i, v := range vs {
+		if !v.IsValid() {
+			err = fmt.Errorf("%v: invalid value for input %v", name(cv), i)
+			return
+		}
+		if !v.Type().ConvertibleTo(ft.In(i)) {
+			err = fmt.Errorf("%v: wrong type for input %v. Got %v, want %v", name(cv), i, v.Type(), ft.In(i))
+			return
+		}
+		in[i] = v.Convert(ft.In(i)).Interface()
+	}
+	return
+}
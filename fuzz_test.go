@@ -0,0 +1,64 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package testutil_test
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func FuzzFuzz_Funcs(f *testing.F) {
+	cases := []struct {
+		Label string
+		In    int
+		Out   string
+	}{
+		{"", 0, "0"},
+		{"", -1, "-1"},
+		{"", math.MaxInt32, "2147483647"},
+	}
+	itoa := func(x int) string { return fmt.Sprint(x) }
+	Test(f, nil, cases, strconv.Itoa)
+	Fuzz(f, nil, cases, strconv.Itoa, itoa)
+}
+
+func FuzzFuzz_Property(f *testing.F) {
+	cases := []struct {
+		Label    string
+		In1, In2 float64
+	}{
+		{"", 0, 0},
+		{"", 3, 4},
+		{"", inf, nan},
+	}
+	property := func(x, y float64) bool {
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return true
+		}
+		h := math.Hypot(x, y)
+		return h >= math.Abs(x) && h >= math.Abs(y)
+	}
+	Fuzz(f, nil, cases, property)
+}
+
+func FuzzFuzz_Interfaces(f *testing.F) {
+	cases := []struct {
+		Label   string
+		In, Out interface{}
+	}{
+		{"", 1, 1.},
+		{"", float32(2), 4.},
+	}
+	square := func(x float64) float64 { return x * x }
+	pow := func(x float64) float64 { return math.Pow(x, 2) }
+	Fuzz(f, 1e-15, cases, square, pow)
+}
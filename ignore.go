@@ -0,0 +1,49 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import "reflect"
+
+// ignoredPath reports whether the part of the outputs at path is ignored.
+func (c *config) ignoredPath(path string) bool {
+	return matchPath(c.ignorePaths, path)
+}
+
+// ignoredField reports whether the struct field f is ignored.
+func (c *config) ignoredField(f reflect.StructField) bool {
+	return c.ignoreFields[f.Name] || c.skipUnexported && f.PkgPath != ""
+}
+
+// mapKeys returns the keys of the map v which are not ignored.
+func (c *config) mapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	if len(c.ignoreKeys) == 0 && (c.cmp == nil || len(c.ignorePaths) == 0) {
+		return keys
+	}
+	n := 0
+	for _, k := range keys {
+		if !c.ignoredKey(k) {
+			keys[n] = k
+			n++
+		}
+	}
+	return keys[:n]
+}
+
+// ignoredKey reports whether the map entry with key k is ignored,
+// by its key or by its path from the current path.
+func (c *config) ignoredKey(k reflect.Value) bool {
+	for _, ignore := range c.ignoreKeys {
+		if ignore(valueOf(k)) {
+			return true
+		}
+	}
+	if d := c.cmp; d != nil && len(c.ignorePaths) > 0 {
+		d.path = append(d.path, step{key: k})
+		defer func() { d.path = d.path[:len(d.path)-1] }()
+		return c.ignoredPath(d.pathString())
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+type stats struct {
+	Iterations int
+	Elapsed    float64
+}
+
+type solution struct {
+	Roots []float64
+	Stats *stats
+	Cache map[string]float64
+}
+
+func TestEqual_Ignore(t *testing.T) {
+	want := solution{[]float64{1, 2}, &stats{3, 0.5}, map[string]float64{"a": 1, "_tmp": 2}}
+	other := solution{[]float64{1, 2}, &stats{3, 0.7}, map[string]float64{"a": 1, "_x": 3, "_y": 4}}
+	underscored := func(k interface{}) bool { return strings.HasPrefix(k.(string), "_") }
+
+	cases := []struct {
+		Label string
+		In1   solution
+		In2   []Option
+		Out   bool
+	}{
+		{"None", other, nil, false},
+		{"Fields", other, []Option{WithIgnoreFields("Elapsed", "Cache")}, true},
+		{"Fields", other, []Option{WithIgnoreFields("Elapsed")}, false},
+		{"Keys", other, []Option{WithIgnoreFields("Elapsed"), WithIgnoreMapKeys(underscored)}, true},
+		{"Paths", other, []Option{WithIgnorePaths(".Stats.Elapsed", ".Cache")}, true},
+		{"Paths", other, []Option{WithIgnorePaths(".Stats.Elapsed", `.Cache["_x"]`)}, false},
+		{"Paths", solution{[]float64{0, 0}, want.Stats, want.Cache}, []Option{WithIgnorePaths(".Roots[*]")}, true},
+		{"Paths", solution{[]float64{0, 2}, want.Stats, want.Cache}, []Option{WithIgnorePaths(".Roots[1]")}, false},
+		{"Paths", solution{[]float64{0, 2}, want.Stats, want.Cache}, []Option{WithIgnorePaths(".Roots[0]")}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := EqualWith(c.In1, want, c.In2...); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestEqual_IgnorePathsMap(t *testing.T) {
+	cases := []struct {
+		Label    string
+		In1, In2 map[string]float64
+		In3      string
+		Out      bool
+	}{
+		{"Missing", map[string]float64{"a": 1}, map[string]float64{"a": 1, "b": 2}, `["b"]`, true},
+		{"Missing", map[string]float64{"a": 1}, map[string]float64{"a": 1, "b": 2}, `["c"]`, false},
+		{"Extra", map[string]float64{"a": 1, "c": 3}, map[string]float64{"a": 1}, `["c"]`, true},
+		{"Bracket", map[string]float64{"a]": 1}, map[string]float64{"a]": 2}, `[*]`, true},
+		{"Bracket", map[string]float64{"a]": 1}, map[string]float64{"a]": 2}, `["a"]`, false},
+		{"Quote", map[string]float64{`"]`: 1}, map[string]float64{`"]`: 2}, `[*]`, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := EqualWith(c.In1, c.In2, WithIgnorePaths(c.In3)); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestTestWith_Ignore(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    int
+		Out   stats
+	}{
+		{"", 3, stats{Iterations: 3}},
+	}
+	solve := func(n int) stats { return stats{n, 1.5} }
+	TestWith(t, cases, []Func{solve}, WithIgnoreFields("Elapsed"))
+	TestWith(t, cases, []Func{solve}, WithIgnorePaths(".Elapsed"), WithMismatches(0))
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Mismatch represents a part of x which does not equal the corresponding
+// part of y, found when comparing them with the WithMismatches option.
+type Mismatch struct {
+	// Path locates the part within x and y, e.g. .Field[3]["key"],
+	// or is empty if x and y themselves do not match.
+	Path string
+
+	// Got is the part of x and Want is the part of y.
+	// Got is nil if the part is missing from x.
+	Got, Want interface{}
+
+	// Numerical is true if Got and Want are numerical, in which case
+	// AbsoluteError and RelativeError are the errors in Got, as in EqualResult.
+	Numerical bool
+
+	AbsoluteError, RelativeError interface{}
+
+	// LengthMismatch is true if Got and Want have different numbers
+	// of elements, fields or keys.
+	LengthMismatch bool
+
+	// MissingValue is true if the field or key at Path is missing from x.
+	MissingValue bool
+
+	// Unmatched is true if the element at Path of a slice compared with the
+	// WithUnordered option does not equal any unmatched element of x.
+	Unmatched bool
+}
+
+// String formats m as a failure message, as reported by Test.
+func (m Mismatch) String() string {
+	switch {
+	case m.LengthMismatch:
+		return fmt.Sprintf("%v: Length mismatch", m.Path)
+	case m.Unmatched:
+		return fmt.Sprintf("%v: No match, want %v", m.Path, m.Want)
+	case m.MissingValue && strings.HasSuffix(m.Path, "]"):
+		return fmt.Sprintf("%v: Missing key, want %v", m.Path, m.Want)
+	case m.MissingValue:
+		return fmt.Sprintf("%v: Missing struct field", m.Path)
+	case m.Numerical:
+		return fmt.Sprintf("%v: Got %v, want %v (δ=%v)", m.Path, m.Got, m.Want, m.RelativeError)
+	}
+	return fmt.Sprintf("%v: Got %v, want %v", m.Path, m.Got, m.Want)
+}
+
+// step represents a step along the path to a part of a value:
+// a struct field, an index or a map key.
+type step struct {
+	field string
+	index int
+	key   reflect.Value
+}
+
+// String formats s as part of a path, e.g. .Field, [3] or ["key"].
+func (s step) String() string {
+	switch {
+	case s.field != "":
+		return "." + s.field
+	case s.key.IsValid():
+		return fmt.Sprintf("[%#v]", valueOf(s.key))
+	}
+	return fmt.Sprintf("[%v]", s.index)
+}
+
+// pathPattern returns a regexp matching the path, formatted as for Mismatch,
+// in which [*] matches any index or key. A quoted key, e.g. ["a]"], may
+// contain any characters.
+func pathPattern(path string) *regexp.Regexp {
+	pattern := strings.ReplaceAll(regexp.QuoteMeta(path), `\[\*\]`, `\[(?:"(?:[^"\\]|\\.)*"|[^"\]]*)\]`)
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// matchPath reports whether path matches any of the patterns.
+func matchPath(patterns []*regexp.Regexp, path string) bool {
+	for _, re := range patterns {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// comparison holds the state of a comparison: the path to the parts being
+// compared and, with the WithMismatches option, the mismatches found.
+type comparison struct {
+	path    []step
+	list    []Mismatch
+	collect bool
+	max     int // the maximum number of mismatches to collect, if positive
+}
+
+// full reports whether no more mismatches can be collected.
+func (d *comparison) full() bool {
+	return !d.collect || d.max > 0 && len(d.list) >= d.max
+}
+
+// pathString returns the current path.
+func (d *comparison) pathString() string {
+	path := make([]string, len(d.path))
+	for i, s := range d.path {
+		path[i] = s.String()
+	}
+	return strings.Join(path, "")
+}
+
+// add adds the mismatch m at the current path.
+func (d *comparison) add(m Mismatch) {
+	if d.full() {
+		return
+	}
+	m.Path = d.pathString()
+	d.list = append(d.list, m)
+}
+
+// record adds a mismatch for the parts xv and yv at the current path,
+// given the result res of comparing them.
+func (d *comparison) record(xv, yv reflect.Value, res EqualResult) {
+	m := Mismatch{
+		Got:            valueOf(xv),
+		Want:           valueOf(yv),
+		Numerical:      res.Numerical,
+		LengthMismatch: res.LengthMismatch,
+	}
+	if res.Numerical {
+		m.AbsoluteError = valueOf(res.AbsoluteError)
+		m.RelativeError = valueOf(res.RelativeError)
+	}
+	d.add(m)
+}
+
+// tagError is a malformed field tag found by equal,
+// which ends the comparison.
+type tagError struct {
+	err error
+}
+
+// compare compares xv and yv as for equal, collecting every mismatch in
+// the result if c has the WithMismatches option, and summarising the errors
+// if c has the WithSummary option. If a malformed field tag is found, then
+// the comparison ends and the result holds the error.
+func compare(xv, yv reflect.Value, c *config) (res EqualResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			e, ok := p.(tagError)
+			if !ok {
+				panic(p)
+			}
+			res = EqualResult{Err: e.err}
+			return
+		}
+		if !res.Ok && c.summary > 0 && summarisable(xv, yv, c.summary) && !c.unorderedAt("") {
+			res.Summary = summarise(xv, yv, c)
+		}
+	}()
+	if !c.collect && len(c.ignorePaths) == 0 && len(c.unorderedPaths) == 0 {
+		return equal(xv, yv, c)
+	}
+	d := &comparison{collect: c.collect, max: c.maxMismatches}
+	cc := *c
+	cc.cmp = d
+	if res = equal(xv, yv, &cc); !res.Ok && len(d.list) == 0 {
+		d.record(xv, yv, res)
+	}
+	res.Mismatches = d.list
+	return
+}
+
+// equalAt compares the parts xv and yv of the values being compared, at the
+// step s from the current path, as for equal. If the parts do not match, and
+// no mismatches were found within them, then they are added to the mismatches.
+// Parts at paths ignored by c are equal.
+func equalAt(s step, xv, yv reflect.Value, c *config) (res EqualResult) {
+	d := c.cmp
+	if d == nil {
+		return equal(xv, yv, c)
+	}
+	d.path = append(d.path, s)
+	defer func() { d.path = d.path[:len(d.path)-1] }()
+
+	if c.ignoredPath(d.pathString()) {
+		res.Ok = true
+		return
+	}
+	n := len(d.list)
+	if res = equal(xv, yv, c); !res.Ok && len(d.list) == n {
+		d.record(xv, yv, res)
+	}
+	return
+}
+
+// missing adds a mismatch for the value yv, which is missing
+// from x at the step s from the current path.
+func missing(s step, yv reflect.Value, c *config) {
+	if d := c.cmp; d != nil {
+		d.path = append(d.path, s)
+		d.add(Mismatch{Want: valueOf(yv), MissingValue: true})
+		d.path = d.path[:len(d.path)-1]
+	}
+}
+
+// unmatched adds a mismatch for the element yv of an unordered slice, at the
+// step s from the current path, which does not equal any element of x.
+func unmatched(s step, yv reflect.Value, c *config) {
+	if d := c.cmp; d != nil {
+		d.path = append(d.path, s)
+		d.add(Mismatch{Want: valueOf(yv), Unmatched: true})
+		d.path = d.path[:len(d.path)-1]
+	}
+}
+
+// more reports whether a comparison should continue after a mismatch,
+// to collect more mismatches.
+func more(c *config) bool {
+	return c.cmp != nil && !c.cmp.full()
+}
+
+// probe returns c for comparisons of parts of the values which are tried
+// rather than reported, e.g. of the elements of unordered slices. The path
+// is still tracked, so that options for paths apply within the parts, but
+// no mismatches are collected.
+func probe(c *config) *config {
+	if c.cmp == nil {
+		return c
+	}
+	cc := *c
+	cc.cmp = &comparison{path: append([]step(nil), c.cmp.path...)}
+	return &cc
+}
+
+// quiet returns c without tracking the path, for comparisons
+// which are not of parts of the values, e.g. of map keys.
+func quiet(c *config) *config {
+	if c.cmp == nil {
+		return c
+	}
+	cc := *c
+	cc.cmp = nil
+	return &cc
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func TestEqual_Mismatches(t *testing.T) {
+	type grid struct {
+		Name   string
+		Values []float64
+		Index  map[int]map[string]float64
+	}
+	want := grid{"g", []float64{1, 2, 3, 4}, map[int]map[string]float64{1: {"a": 1, "b": 2}, 2: {"c": 3}}}
+	type point struct{ X, Y float64 }
+	p, q := &point{1, 2}, &point{1, 3}
+
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		In3      int
+		Out      []string
+	}{
+		{"Equal", want, want, 0, nil},
+		{"Scalar", 1.5, 1., 0, []string{": Got 1.5, want 1 (δ=0.5)"}},
+		{"Slice", []float64{1, 0, 3, 0}, []float64{1, 2, 3, 4}, 0, []string{
+			"[1]: Got 0, want 2 (δ=-1)",
+			"[3]: Got 0, want 4 (δ=-1)",
+		}},
+		{"Limit", []float64{0, 0, 0, 0}, []float64{1, 2, 3, 4}, 2, []string{
+			"[0]: Got 0, want 1 (δ=-1)",
+			"[1]: Got 0, want 2 (δ=-1)",
+		}},
+		{"Nested", grid{"h", []float64{1, 2, 0, 4}, map[int]map[string]float64{1: {"a": 1, "b": 3}, 2: {"d": 3}}}, want, 0, []string{
+			".Name: Got h, want g",
+			".Values[2]: Got 0, want 3 (δ=-1)",
+			`.Index[1]["b"]: Got 3, want 2 (δ=0.5)`,
+			`.Index[2]["c"]: Missing key, want 3`,
+		}},
+		// a pair of pointers is compared again wherever it appears
+		{"Shared", []*point{p, p}, []*point{q, q}, 0, []string{
+			"[0].Y: Got 2, want 3 (δ=-0.3333333333333333)",
+			"[1].Y: Got 2, want 3 (δ=-0.3333333333333333)",
+		}},
+		{"Length", [][]int{{1}, {1, 2}}, [][]int{{2}, {1}}, 0, []string{
+			"[0][0]: Got 1, want 2 (δ=-0.5)",
+			"[1]: Length mismatch",
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			res := EqualWith(c.In1, c.In2, WithMismatches(c.In3))
+			var got []string
+			for _, m := range res.Mismatches {
+				got = append(got, m.String())
+			}
+			if res.Ok != (c.Out == nil) || !Equal(got, c.Out, nil).Ok {
+				t.Errorf("Error: wanted %q, got %q", c.Out, got)
+			}
+		})
+	}
+}
+
+func TestTestWith_Mismatches(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out []float64
+	}{
+		{"", []float64{1, 2, 3}, []float64{1, 2.5, 3.5}},
+	}
+	id := func(x []float64) []float64 { return x }
+
+	var errs []string
+	TestWith(t, cases, []Func{id}, WithMismatches(0), WithReporter(recorder(&errs)))
+	want := []string{"[0][1]: Got 2, want 2.5 (δ=-0.2)\n[0][2]: Got 3, want 3.5 (δ=-0.14285714285714285)"}
+	if !Equal(errs, want, nil).Ok {
+		t.Errorf("Error: wanted %q, got %q", want, errs)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"regexp"
+	"runtime"
+	"testing"
+)
+
+// Option represents an optional setting for TestWith, CheckWith or EqualWith.
+type Option func(*config)
+
+// Reporter reports the errors for a failed case to t, which is the
+// sub-test for that case if t supports sub-tests. The default Reporter
+// calls t.Error for each error.
+type Reporter func(t testing.TB, errs []error)
+
+// config holds the settings used to run and compare cases.
+type config struct {
+	tol            Tolerance
+	tolFunc        ToleranceFunc
+	nans           NaNPolicy
+	zeros          ZeroPolicy
+	complexes      ComplexPolicy
+	relative       RelativePolicy
+	collect        bool
+	maxMismatches  int
+	cmp            *comparison // the state of the current comparison
+	ignoreFields   map[string]bool
+	ignoreKeys     []func(key interface{}) bool
+	ignorePaths    []*regexp.Regexp
+	unordered      bool
+	unorderedPaths []*regexp.Regexp
+	summary        int
+	visited        map[visit]bool // the pairs being compared by the current comparison
+	skipUnexported bool
+	opaque         bool // comparing the internals of a type with no exported fields
+	workers        int
+	comparers      map[int]func(got, want interface{}) bool
+	errors         map[int]ErrorMatch
+	reporter       Reporter
+	seed           int64
+	seeded         bool
+	maxFailures    int
+}
+
+// newConfig returns the default config modified by opts.
+func newConfig(opts ...Option) *config {
+	c := &config{
+		workers:  1,
+		reporter: reportErrors,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// setTolerance sets the tolerance of c, which is either a fixed tolerance
+// or a ToleranceFunc.
+func (c *config) setTolerance(tolerance interface{}) {
+	switch f := tolerance.(type) {
+	case ToleranceFunc:
+		c.tol, c.tolFunc = Tolerance{}, f
+	case func([]interface{}, interface{}) Tolerance:
+		c.tol, c.tolFunc = Tolerance{}, f
+	default:
+		c.tol, c.tolFunc = validateTolerance(tolerance), nil
+	}
+}
+
+// withTolerance returns a copy of c with the fixed tolerance tol.
+func (c *config) withTolerance(tol Tolerance) *config {
+	cc := *c
+	cc.tol, cc.tolFunc = tol, nil
+	return &cc
+}
+
+// reportErrors is the default Reporter.
+func reportErrors(t testing.TB, errs []error) {
+	t.Helper()
+	for _, err := range errs {
+		t.Error(err)
+	}
+}
+
+// WithTolerance sets the numerical tolerance used to compare outputs.
+// It accepts the same values as the tolerance argument of Test.
+func WithTolerance(tolerance interface{}) Option {
+	return func(c *config) { c.setTolerance(tolerance) }
+}
+
+// WithNaNPolicy sets the policy for comparing NaNs. By default, any NaN equals
+// any other NaN. The policy also applies to EqualWith, e.g. when it is used
+// inside the predicate passed to Any or All.
+func WithNaNPolicy(p NaNPolicy) Option {
+	return func(c *config) { c.nans = p }
+}
+
+// WithZeroPolicy sets the policy for comparing zeros of opposite sign.
+// By default, +0 and -0 are not equal. Like WithNaNPolicy, it also
+// applies to EqualWith.
+func WithZeroPolicy(p ZeroPolicy) Option {
+	return func(c *config) { c.zeros = p }
+}
+
+// WithComplexPolicy sets the policy for applying the tolerance to complex
+// numbers. By default, the real and imaginary parts are compared separately.
+func WithComplexPolicy(p ComplexPolicy) Option {
+	return func(c *config) { c.complexes = p }
+}
+
+// WithRelativePolicy sets the definition of the relative error used for
+// the Rel bound of the tolerance and reported for failures. By default, it
+// is relative to the expected value.
+func WithRelativePolicy(p RelativePolicy) Option {
+	return func(c *config) { c.relative = p }
+}
+
+// WithMismatches compares every part of each output, collecting at most n
+// mismatches with the path to each, instead of stopping at the first part
+// which does not match. Every mismatch is reported. If n is less than 1,
+// every mismatch is collected.
+func WithMismatches(n int) Option {
+	return func(c *config) {
+		c.collect = true
+		c.maxMismatches = n
+	}
+}
+
+// WithSummary reports summary statistics of the errors, and the elements
+// around the worst element, for outputs which are numerical slices or arrays
+// with at least n elements, instead of only the first element out of
+// tolerance. If n is less than 1, every such output is summarised.
+// Outputs compared as multisets with the WithUnordered option, whose
+// elements are not compared index by index, are not summarised.
+func WithSummary(n int) Option {
+	return func(c *config) {
+		if n < 1 {
+			n = 1
+		}
+		c.summary = n
+	}
+}
+
+// WithUnexported sets whether the unexported fields of structs are compared.
+// By default they are, in the same way as exported fields, except that
+// funcs held by unexported fields must be identical rather than equivalent,
+// as must integers within structs with no exported fields, such as
+// time.Time and big.Int (see Equal).
+func WithUnexported(include bool) Option {
+	return func(c *config) { c.skipUnexported = !include }
+}
+
+// WithIgnoreFields ignores the struct fields with the given names wherever
+// they appear in the outputs, as for a testutil:"ignore" tag (see Equal).
+func WithIgnoreFields(names ...string) Option {
+	return func(c *config) {
+		if c.ignoreFields == nil {
+			c.ignoreFields = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.ignoreFields[name] = true
+		}
+	}
+}
+
+// WithIgnoreMapKeys ignores the entries of maps, in both the actual and
+// expected outputs, whose keys satisfy ignore.
+func WithIgnoreMapKeys(ignore func(key interface{}) bool) Option {
+	return func(c *config) { c.ignoreKeys = append(c.ignoreKeys, ignore) }
+}
+
+// WithIgnorePaths ignores the parts of the outputs at the given paths, which
+// are formatted as for Mismatch, e.g. .Stats.Elapsed, [2].X or ["key"].
+// Within a path, [*] matches any index or key, e.g. .Points[*].Time.
+// A map entry at an ignored path is ignored even if it is missing from
+// one of the outputs.
+func WithIgnorePaths(paths ...string) Option {
+	return func(c *config) {
+		for _, path := range paths {
+			c.ignorePaths = append(c.ignorePaths, pathPattern(path))
+		}
+	}
+}
+
+// WithUnordered compares slices and arrays as multisets, so that x equals y
+// if each element of y equals a distinct element of x, in any order, e.g. for
+// roots or eigenvalues returned in an implementation-defined order. Elements
+// are matched within the tolerance, which need not be transitive, so the
+// elements are assigned to each other rather than sorted.
+//
+// If paths are given, formatted as for WithIgnorePaths, then only the slices
+// and arrays at those paths are compared as multisets, e.g. "" for an output
+// which is a slice, or .Roots for a field of an output. Within a slice
+// compared as a multiset, the path of an element is that of its index in y,
+// e.g. [2], whichever element of x it is compared with.
+func WithUnordered(paths ...string) Option {
+	return func(c *config) {
+		c.unordered = true
+		for _, path := range paths {
+			c.unorderedPaths = append(c.unorderedPaths, pathPattern(path))
+		}
+	}
+}
+
+// WithParallel evaluates the cases concurrently using at most workers
+// goroutines, as for TestParallel. If workers is less than 1, GOMAXPROCS is used.
+func WithParallel(workers int) Option {
+	return func(c *config) {
+		if workers < 1 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+		c.workers = workers
+	}
+}
+
+// WithComparer replaces the comparison of output i of every case with
+// the function f, which reports whether got equals want. The tolerance
+// does not apply to that output.
+func WithComparer(i int, f func(got, want interface{}) bool) Option {
+	return func(c *config) {
+		if c.comparers == nil {
+			c.comparers = make(map[int]func(got, want interface{}) bool)
+		}
+		c.comparers[i] = f
+	}
+}
+
+// WithErrorMatch matches the actual errors for output i of every case to
+// the expected errors in the cases using match, rather than requiring them
+// to be equal. For example, with MatchIs, a wrapped error matches a
+// sentinel error given in the cases. An expected error created by ErrorIs,
+// ErrorAs, ErrorContains or ErrorMatches always uses its own match.
+func WithErrorMatch(i int, match ErrorMatch) Option {
+	return func(c *config) {
+		if c.errors == nil {
+			c.errors = make(map[int]ErrorMatch)
+		}
+		c.errors[i] = match
+	}
+}
+
+// WithReporter sets the Reporter used to report failed cases.
+func WithReporter(r Reporter) Option {
+	return func(c *config) { c.reporter = r }
+}
+
+// WithSeed sets the seed used to generate random arguments when comparing
+// outputs that are functions, making the comparison reproducible. By default
+// the seed is taken from the current time.
+func WithSeed(seed int64) Option {
+	return func(c *config) {
+		c.seed = seed
+		c.seeded = true
+	}
+}
+
+// WithMaxFailures stops testing after n cases have failed, skipping the
+// remaining cases. If n is less than 1, every case is tested.
+func WithMaxFailures(n int) Option {
+	return func(c *config) { c.maxFailures = n }
+}
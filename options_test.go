@@ -0,0 +1,104 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+// recorder returns a Reporter which records the errors
+// reported for each case instead of failing the test.
+func recorder(errs *[]string) Reporter {
+	return func(t testing.TB, es []error) {
+		for _, err := range es {
+			*errs = append(*errs, err.Error())
+		}
+	}
+}
+
+func TestTestWith_Tolerance(t *testing.T) {
+	cases := []struct {
+		Label         string
+		In1, In2, Out float64
+	}{
+		{"", 1, 1, math.Sqrt2},
+		{"", 1, 2, 2.236},
+	}
+	TestWith(t, cases, []Func{math.Hypot}, WithTolerance(1e-3))
+	TestWith(t, cases, []Func{math.Hypot}, WithTolerance(1e-3), WithParallel(2))
+}
+
+func TestTestWith_Comparer(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    string
+		Out1  float64
+		Out2  error
+	}{
+		{"", "1.5", 1.5, nil},
+		{"", "x", 0, strconv.ErrSyntax},
+	}
+	parse := func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+	sameError := func(got, want interface{}) bool {
+		if got == nil || want == nil {
+			return got == want
+		}
+		return strings.HasSuffix(got.(error).Error(), want.(error).Error())
+	}
+	TestWith(t, cases, []Func{parse}, WithComparer(1, sameError))
+}
+
+func TestTestWith_Reporter(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out float64
+	}{
+		{"", 0, 0},
+		{"", 1, 2},
+		{"", 4, 2},
+		{"", 9, 4},
+	}
+
+	var errs []string
+	TestWith(t, cases, []Func{math.Sqrt}, WithReporter(recorder(&errs)))
+	if want := []string{"[0]: Got 1, want 2 (δ=-0.5)", "[0]: Got 3, want 4 (δ=-0.25)"}; !Equal(errs, want, nil).Ok {
+		t.Errorf("Error: wanted %q, got %q", want, errs)
+	}
+
+	errs = nil
+	TestWith(t, cases, []Func{math.Sqrt}, WithReporter(recorder(&errs)), WithMaxFailures(1))
+	if len(errs) != 1 {
+		t.Errorf("Error: wanted 1 failure, got %q", errs)
+	}
+}
+
+func TestTestWith_Seed(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out interface{}
+	}{
+		{"", math.Sin, math.Cos},
+	}
+	id := func(f func(float64) float64) func(float64) float64 { return f }
+
+	var errs1, errs2 []string
+	TestWith(t, cases, []Func{id}, WithSeed(1), WithReporter(recorder(&errs1)))
+	TestWith(t, cases, []Func{id}, WithSeed(1), WithReporter(recorder(&errs2)))
+	if len(errs1) != 1 || !strings.Contains(errs1[0], "counterexample") || !Equal(errs1, errs2, nil).Ok {
+		t.Errorf("Error: wanted identical counterexamples, got %q and %q", errs1, errs2)
+	}
+
+	// the counterexample is also reported with the mismatches
+	var errs3 []string
+	TestWith(t, cases, []Func{id}, WithSeed(1), WithMismatches(0), WithReporter(recorder(&errs3)))
+	if len(errs3) != 1 || !strings.Contains(errs3[0], "counterexample") {
+		t.Errorf("Error: wanted counterexample, got %q", errs3)
+	}
+}
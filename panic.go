@@ -0,0 +1,86 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+)
+
+// panicked represents a recovered panic.
+type panicked struct {
+	value interface{}
+	stack []byte
+}
+
+// call calls the function fv with the arguments in, recovering
+// from any panic, which is returned in p along with its stack trace.
+func call(fv reflect.Value, in []reflect.Value) (out []reflect.Value, p *panicked) {
+	defer func() {
+		if r := recover(); r != nil {
+			p = &panicked{r, debug.Stack()}
+		}
+	}()
+	out = fv.Call(in)
+	return
+}
+
+// expectsPanic reports whether the expected panic want, given by the Panic
+// field of a case, expects a panic. A panic is expected if want is true,
+// a non-empty string, or any other non-zero value.
+func expectsPanic(want reflect.Value) bool {
+	return want.IsValid() && !want.IsZero()
+}
+
+// matchPanic reports whether the panic p matches the expected panic want.
+// If want is true, any panic matches. If want is a string, a panic matches if
+// its message contains want. Otherwise, the panic value must equal want.
+func matchPanic(p *panicked, want reflect.Value) bool {
+	switch want.Kind() {
+	case reflect.Bool:
+		return true
+	case reflect.String:
+		return strings.Contains(fmt.Sprint(p.value), want.String())
+	}
+	return reflect.DeepEqual(p.value, want.Interface())
+}
+
+// handlePanic returns the result of a case which expects the panic want,
+// given the panic p from calling the function, which is nil if it did not panic.
+func handlePanic(p *panicked, want reflect.Value) (r Result) {
+	r.Output = -1
+	r.Want = want.Interface()
+	if p == nil {
+		r.Err = fmt.Errorf("Got no panic, want panic %v", describePanic(want))
+		return
+	}
+	r.Got = p.value
+	if r.Ok = matchPanic(p, want); !r.Ok {
+		r.Err = fmt.Errorf("Got panic %q, want panic %v\n%s", fmt.Sprint(p.value), describePanic(want), p.stack)
+	}
+	return
+}
+
+// describePanic describes the expected panic want.
+func describePanic(want reflect.Value) string {
+	switch want.Kind() {
+	case reflect.Bool:
+		return "of any value"
+	case reflect.String:
+		return fmt.Sprintf("containing %q", want.String())
+	}
+	return fmt.Sprintf("%v", want)
+}
+
+// unexpectedPanic returns the result of a case for which
+// the function f (f1 or f2) panicked unexpectedly with p.
+func unexpectedPanic(f string, p *panicked) (r Result) {
+	r.Output = -1
+	r.Got = p.value
+	r.Err = fmt.Errorf("%v panicked: %v\n%s", f, p.value, p.stack)
+	return
+}
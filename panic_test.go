@@ -0,0 +1,93 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+var errDomain = errors.New("domain error")
+
+// sqrt returns the square root of the integer x,
+// panicking if x is negative or not a perfect square.
+func sqrt(x int) int {
+	if x < 0 {
+		panic(errDomain)
+	}
+	for y := 0; y <= x; y++ {
+		if y*y == x {
+			return y
+		}
+	}
+	panic("not a perfect square")
+}
+
+func TestTest_Panics(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    int
+		Out   int
+		Panic interface{}
+	}{
+		{"", 4, 2, nil},
+		{"", 5, 0, true},
+		{"", 6, 0, "perfect square"},
+		{"", -1, 0, errDomain},
+	}
+	Test(t, nil, cases, sqrt)
+}
+
+func TestTest_PanicsTagged(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In      int
+		Message string `testutil:"panic"`
+		Out     int
+	}{
+		{"", 9, "", 3},
+		{"", 10, "perfect", 0},
+	}
+	Test(t, nil, cases, sqrt)
+	Test(t, nil, cases, sqrt, sqrt)
+}
+
+func TestCheck_Panics(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    int
+		Out   int
+		Panic string
+	}{
+		{"Unexpected", 5, 0, ""},
+		{"Missing", 4, 2, "perfect square"},
+		{"Mismatch", -4, 0, "perfect square"},
+	}
+
+	rep, err := Check(nil, cases, sqrt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"f1 panicked: not a perfect square\n",
+		`Got no panic, want panic containing "perfect square"`,
+		`Got panic "domain error", want panic containing "perfect square"` + "\n",
+	}
+	if len(rep) != len(want) {
+		t.Fatalf("Error: wanted %v results, got %v", len(want), rep)
+	}
+	for i, r := range rep {
+		if r.Ok || r.Output != -1 || !strings.HasPrefix(r.Err.Error(), want[i]) {
+			t.Errorf("Error: wanted %q, got %v", want[i], r.Err)
+		}
+	}
+	// the stack trace is included for unexpected panics
+	if !strings.Contains(rep[0].Err.Error(), "sqrt") {
+		t.Errorf("Error: wanted stack trace, got %v", rep[0].Err)
+	}
+}
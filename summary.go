@@ -0,0 +1,149 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"reflect"
+	"strings"
+)
+
+// Summary represents summary statistics of the errors in a numerical slice
+// or array x compared to y, found when comparing them with the WithSummary
+// option.
+type Summary struct {
+	// Len is the number of elements and Failures is the number
+	// which are not equal within the tolerance. Elements at paths
+	// ignored by WithIgnorePaths are counted in Len, but otherwise skipped.
+	Len, Failures int
+
+	// Worst is the index of the element out of tolerance
+	// with the greatest relative error.
+	Worst int
+
+	// The maxima, means and root mean squares of the magnitudes of the
+	// absolute and relative errors. The means and RMS are of the elements
+	// with finite errors.
+	MaxAbs, MeanAbs, RMSAbs float64
+	MaxRel, MeanRel, RMSRel float64
+
+	// Excerpt holds the elements around the worst element,
+	// with their paths, e.g. [17].
+	Excerpt []Mismatch
+}
+
+// String formats s as a failure message, as reported by Test.
+func (s Summary) String() string {
+	lines := []string{
+		fmt.Sprintf("%v of %v elements out of tolerance, worst at [%v]", s.Failures, s.Len, s.Worst),
+		fmt.Sprintf("\tabsolute error: max %.3g, mean %.3g, rms %.3g", s.MaxAbs, s.MeanAbs, s.RMSAbs),
+		fmt.Sprintf("\trelative error: max %.3g, mean %.3g, rms %.3g", s.MaxRel, s.MeanRel, s.RMSRel),
+	}
+	for _, m := range s.Excerpt {
+		marker := " "
+		if m.Path == fmt.Sprintf("[%v]", s.Worst) {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("\t%v %v", marker, m))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// excerpt is the number of elements either side of
+// the worst element included in a Summary.
+const excerpt = 2
+
+// summarise returns the Summary of the errors in the numerical slice or array
+// xv compared to yv, which have equal lengths, using the settings in c.
+// Elements at paths ignored by c are skipped.
+func summarise(xv, yv reflect.Value, c *config) (s *Summary) {
+	ignored := func(i int) bool { return c.ignoredPath(step{index: i}.String()) }
+	c = quiet(c)
+	n := xv.Len()
+	s = &Summary{Len: n}
+	finite, worst := 0, 0.
+	for i := 0; i < n; i++ {
+		if ignored(i) {
+			continue
+		}
+		res := equal(xv.Index(i), yv.Index(i), c)
+		abs, rel := magnitude(res.AbsoluteError), magnitude(res.RelativeError)
+		if math.IsNaN(rel) { // NaNs are the worst
+			rel = math.Inf(1)
+		}
+		if !res.Ok {
+			if s.Failures++; s.Failures == 1 || rel > worst {
+				s.Worst, worst = i, rel
+			}
+		}
+		s.MaxAbs = math.Max(s.MaxAbs, abs)
+		s.MaxRel = math.Max(s.MaxRel, rel)
+		if !math.IsInf(abs, 0) && !math.IsInf(rel, 0) && !math.IsNaN(abs) {
+			finite++
+			s.MeanAbs += abs
+			s.MeanRel += rel
+			s.RMSAbs += abs * abs
+			s.RMSRel += rel * rel
+		}
+	}
+	if finite > 0 {
+		s.MeanAbs /= float64(finite)
+		s.MeanRel /= float64(finite)
+		s.RMSAbs = math.Sqrt(s.RMSAbs / float64(finite))
+		s.RMSRel = math.Sqrt(s.RMSRel / float64(finite))
+	}
+
+	for i := s.Worst - excerpt; i <= s.Worst+excerpt; i++ {
+		if i < 0 || i >= n || ignored(i) {
+			continue
+		}
+		res := equal(xv.Index(i), yv.Index(i), c)
+		s.Excerpt = append(s.Excerpt, Mismatch{
+			Path:          fmt.Sprintf("[%v]", i),
+			Got:           valueOf(xv.Index(i)),
+			Want:          valueOf(yv.Index(i)),
+			Numerical:     true,
+			AbsoluteError: valueOf(res.AbsoluteError),
+			RelativeError: valueOf(res.RelativeError),
+		})
+	}
+	return
+}
+
+// summarisable reports whether xv and yv are numerical slices or arrays
+// of equal length, with at least min elements.
+func summarisable(xv, yv reflect.Value, min int) bool {
+	for _, v := range []reflect.Value{xv, yv} {
+		if k := v.Kind(); k != reflect.Slice && k != reflect.Array || !numeric(v.Type().Elem().Kind()) {
+			return false
+		}
+	}
+	return xv.Len() == yv.Len() && xv.Len() >= min
+}
+
+// numeric reports whether k is a real or complex numerical kind.
+func numeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// magnitude returns the magnitude of the real or complex error v,
+// or 0 if v is invalid.
+func magnitude(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Float64:
+		return math.Abs(v.Float())
+	case reflect.Complex128:
+		return cmplx.Abs(v.Complex())
+	}
+	return 0
+}
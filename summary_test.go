@@ -0,0 +1,76 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func TestEqual_Summary(t *testing.T) {
+	n := 100
+	x, y := make([]float64, n), make([]float64, n)
+	for i := range y {
+		y[i] = float64(i + 1)
+		x[i] = y[i] * (1 + 1e-10)
+	}
+	x[17] = y[17] * 1.01
+	x[50] = y[50] * 0.999
+
+	res := EqualWith(x, y, WithTolerance(1e-6), WithSummary(10))
+	s := res.Summary
+	if res.Ok || s == nil {
+		t.Fatalf("Error: wanted a summary, got %v", res)
+	}
+	if s.Len != n || s.Failures != 2 || s.Worst != 17 {
+		t.Errorf("Error: wanted 2 of %v failures, worst at 17, got %v of %v, worst at %v", n, s.Failures, s.Len, s.Worst)
+	}
+	if !Equal(s.MaxRel, 0.01, 1e-9).Ok || !Equal(s.MaxAbs, 0.18, 1e-9).Ok {
+		t.Errorf("Error: wanted max errors 0.18 and 0.01, got %v and %v", s.MaxAbs, s.MaxRel)
+	}
+	if want := (0.01 + 0.001 + 98e-10) / float64(n); !Equal(s.MeanRel, want, 1e-6).Ok {
+		t.Errorf("Error: wanted mean relative error %v, got %v", want, s.MeanRel)
+	}
+	if want := math.Sqrt((1e-4 + 1e-6 + 98e-20) / float64(n)); !Equal(s.RMSRel, want, 1e-6).Ok {
+		t.Errorf("Error: wanted RMS relative error %v, got %v", want, s.RMSRel)
+	}
+	if len(s.Excerpt) != 5 || s.Excerpt[2].Path != "[17]" {
+		t.Errorf("Error: wanted 5 elements around [17], got %v", s.Excerpt)
+	}
+
+	if res := EqualWith(x[:5], y[:5], WithTolerance(1e-6), WithSummary(10)); res.Summary != nil {
+		t.Errorf("Error: wanted no summary for short slices, got %v", res.Summary)
+	}
+	if res := EqualWith([2]complex128{1, 2i}, [2]complex128{1, 3i}, WithTolerance(1e-6), WithSummary(0)); res.Summary == nil || res.Summary.Worst != 1 {
+		t.Errorf("Error: wanted worst at 1, got %v", res.Summary)
+	}
+	if res := EqualWith([]float64{1, 2, 3, 4}, []float64{4, 3, 2, 5}, WithUnordered(), WithSummary(1)); res.Ok || res.Summary != nil {
+		t.Errorf("Error: wanted no summary for unordered slices, got %v", res.Summary)
+	}
+	res = EqualWith([]float64{1, 2, 3, 99, 7}, []float64{1, 2, 3, 4, 8}, WithIgnorePaths("[3]"), WithSummary(1))
+	if s := res.Summary; s == nil || s.Failures != 1 || s.Worst != 4 || s.MaxAbs != 1 || len(s.Excerpt) != 2 {
+		t.Errorf("Error: wanted 1 failure at [4], skipping [3], got %v", s)
+	}
+}
+
+func TestTestWith_Summary(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out []float64
+	}{
+		{"", []float64{1, 2, 3, 4}, []float64{1, 2, 3.5, 4}},
+	}
+	id := func(x []float64) []float64 { return x }
+
+	var errs []string
+	TestWith(t, cases, []Func{id}, WithSummary(0), WithReporter(recorder(&errs)))
+	if len(errs) != 1 || !strings.HasPrefix(errs[0], "[0]: 1 of 4 elements out of tolerance, worst at [2]") ||
+		!strings.Contains(errs[0], "> [2]: Got 3, want 3.5") {
+		t.Errorf("Error: wanted a summary, got %q", errs)
+	}
+}
@@ -7,6 +7,7 @@ package testutil
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -19,15 +20,95 @@ import (
 //
 // If 2 functions are provided, then their respective outputs are
 // compared, using the inputs provided in each case.
-func Test(t *testing.T, tolerance interface{}, cases Cases, funcs ...Func) {
-	tol := validateTolerance(tolerance)
-	cvs, nc, nfc, err := parseCases(cases)
+//
+// Outputs are compared as for Equal, with numerical values required
+// to be equal within the tolerance. If tolerance is a ToleranceFunc, it
+// gives the tolerance for each output from the inputs of the case. A case
+// can override the tolerance with its special Tolerance field (see Cases).
+//
+// If a function panics, then the sub-test for that case fails, reporting
+// the panic and its stack trace, and the remaining cases are still tested.
+// A case can instead require the first function to panic by setting its
+// special Panic field (see Cases) to true, to require any panic, or to a
+// string, to require a panic whose message contains the string. For any
+// other non-zero value, the panic value must equal the Panic field.
+// The names Panic and Tolerance are reserved for these special fields,
+// unless the fields have other types (see Cases).
+//
+// Test is equivalent to TestWith with the WithTolerance option.
+func Test(t testing.TB, tolerance interface{}, cases Cases, funcs ...Func) {
+	TestWith(t, cases, funcs, WithTolerance(tolerance))
+}
+
+// TestParallel is like Test, but the cases are evaluated concurrently
+// by at most workers goroutines, so the functions being tested must be
+// safe for concurrent use. If workers is less than 1, GOMAXPROCS is used.
+//
+// The sub-tests are still run in the order of the cases, each waiting
+// for its own case to be evaluated, so failures are always reported in
+// the same order regardless of how the evaluations are scheduled.
+//
+// TestParallel is equivalent to TestWith with the WithTolerance and
+// WithParallel options.
+func TestParallel(t testing.TB, workers int, tolerance interface{}, cases Cases, funcs ...Func) {
+	TestWith(t, cases, funcs, WithTolerance(tolerance), WithParallel(workers))
+}
+
+// TestWith is a generic case-driven testing function like Test, which
+// accepts a slice of cases, a slice of either 1 or 2 functions to be
+// tested and any number of options. A sub-test is run for each case.
+//
+// By default, outputs must be exactly equal and cases are tested
+// serially; see the Option functions for the available settings.
+//
+// If t is a *testing.T, each case is run as a sub-test. Otherwise, e.g. for
+// a *testing.B or *testing.F, the cases are run directly on t and failures are
+// prefixed by the case label, so a table can also be verified before
+// benchmarking or fuzzing, or by any other implementation of testing.TB.
+func TestWith(t testing.TB, cases Cases, funcs []Func, opts ...Option) {
+	c := newConfig(opts...)
+	tab, err := newTable(cases, funcs)
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	eval := func(i int) []Result { return tab.evaluate(i, c) }
+	if c.workers > 1 {
+		var stop func()
+		eval, stop = parallel(tab.nc, c.workers, eval)
+		defer stop()
+	}
+
+	failures := 0
+	for i := 0; i < tab.nc; i++ {
+		if c.maxFailures > 0 && failures == c.maxFailures {
+			t.Logf("skipping %v cases after %v failures", tab.nc-i, failures)
+			return
+		}
+		if !subtest(t, tab.cvs.Index(i), func() []Result { return eval(i) }, c.reporter) {
+			failures++
+		}
+	}
+}
+
+// table represents a parsed slice of cases and the function(s) to test.
+type table struct {
+	cvs       reflect.Value
+	nc        int
+	f1v, f2v  reflect.Value
+	nIn, nOut int
+}
+
+// newTable parses cases and funcs, and checks that the cases have the
+// right number of input and output fields for the function(s).
+func newTable(cases Cases, funcs []Func) (tab table, err error) {
+	cvs, nc, nfc, err := parseCases(cases)
+	if err != nil {
+		return
+	}
 	f1v, f2v, err := parseFuncs(funcs...)
 	if err != nil {
-		t.Fatal(err)
+		return
 	}
 	nIn := f1v.Type().NumIn()
 	nOut := f1v.Type().NumOut()
@@ -35,48 +116,255 @@ func Test(t *testing.T, tolerance interface{}, cases Cases, funcs ...Func) {
 	switch f2v.IsNil() {
 	case true: // 1 func
 		if nfc-1 != nIn+nOut {
-			t.Fatalf("wrong number of input/output slices. Got %v, want %v", nfc-1, nIn+nOut)
+			err = fmt.Errorf("wrong number of input/output slices. Got %v, want %v", nfc-1, nIn+nOut)
+			return
 		}
 	case false: // 2 funcs
 		if nfc-1 != nIn+nOut && nfc-1 != nIn { // outputs are optional with 2 funcs
-			t.Fatalf("wrong number of input slices. Got %v, want %v", nfc-1, nIn)
+			err = fmt.Errorf("wrong number of input slices. Got %v, want %v", nfc-1, nIn)
+			return
+		}
+	}
+	for i := 0; i < nOut; i++ {
+		if err = checkTags(f1v.Type().Out(i)); err != nil {
+			return
+		}
+	}
+	tab = table{cvs, nc, f1v, f2v, nIn, nOut}
+	return
+}
+
+// parallel evaluates eval for the cases 0 to n-1, in order, using a pool of
+// workers. It returns a function which waits for, and returns, the result of a
+// case and a function which stops any cases that have not started.
+func parallel(n, workers int, eval func(int) []Result) (result func(int) []Result, stop func()) {
+	// each result is buffered so the workers never wait for the subtests
+	results := make([]chan []Result, n)
+	for i := range results {
+		results[i] = make(chan []Result, 1)
+	}
+	jobs := make(chan int)
+	done := make(chan struct{})
+	go func() {
+		defer close(jobs)
+		for i := 0; i < n; i++ {
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
 		}
+	}()
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				results[i] <- eval(i)
+			}
+		}()
 	}
+	result = func(i int) []Result { return <-results[i] }
+	stop = func() { close(done) }
+	return
+}
 
-	for i := 0; i < nc; i++ {
-		subtest(t, cvs.Index(i), f1v, f2v, nIn, nOut, tol)
+// subtest runs a subtest for a case, reporting the errors for the
+// results returned by eval with report. It returns false if there were
+// any errors.
+//
+// If t does not support subtests, the case is run directly on t.
+func subtest(t testing.TB, cv reflect.Value, eval func() []Result, report Reporter) (ok bool) {
+	ok = true
+	run := func(t testing.TB) {
+		if errs := Report(eval()).errors(); len(errs) > 0 {
+			ok = false
+			report(t, errs)
+		}
 	}
+	if tt, isT := t.(*testing.T); isT {
+		tt.Run(name(cv), func(t *testing.T) { run(t) })
+	} else {
+		run(labelledTB{t, name(cv)})
+	}
+	return
+}
+
+// labelledTB is a testing.TB which prefixes its messages with the label
+// of a case. It stands in for a subtest where t does not support them.
+type labelledTB struct {
+	testing.TB
+	label string
 }
 
-// subtest runs a subtest for a case.
-func subtest(t *testing.T, cv, f1v, f2v reflect.Value, nIn, nOut int, tol float64) {
-	t.Run(name(cv), func(t *testing.T) {
-		var in, out, res []reflect.Value
+// prefix prefixes the message formatted from args with the label.
+func (t labelledTB) prefix(args ...interface{}) string {
+	msg := strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+	if t.label == "" {
+		return msg
+	}
+	return t.label + ": " + msg
+}
 
-		in = sliceFrom(cv, 1, nIn)
-		if f2v.IsNil() {
-			out = sliceFrom(cv, 1+nIn, nOut)
-		} else {
-			out = f2v.Call(in)
+func (t labelledTB) Error(args ...interface{}) { t.TB.Helper(); t.TB.Error(t.prefix(args...)) }
+func (t labelledTB) Fatal(args ...interface{}) { t.TB.Helper(); t.TB.Fatal(t.prefix(args...)) }
+func (t labelledTB) Log(args ...interface{})   { t.TB.Helper(); t.TB.Log(t.prefix(args...)) }
+func (t labelledTB) Skip(args ...interface{})  { t.TB.Helper(); t.TB.Skip(t.prefix(args...)) }
+
+func (t labelledTB) Errorf(format string, args ...interface{}) {
+	t.TB.Helper()
+	t.TB.Error(t.prefix(fmt.Sprintf(format, args...)))
+}
+
+func (t labelledTB) Fatalf(format string, args ...interface{}) {
+	t.TB.Helper()
+	t.TB.Fatal(t.prefix(fmt.Sprintf(format, args...)))
+}
+
+func (t labelledTB) Logf(format string, args ...interface{}) {
+	t.TB.Helper()
+	t.TB.Log(t.prefix(fmt.Sprintf(format, args...)))
+}
+
+func (t labelledTB) Skipf(format string, args ...interface{}) {
+	t.TB.Helper()
+	t.TB.Skip(t.prefix(fmt.Sprintf(format, args...)))
+}
+
+// evaluate calls the function(s) for case i and returns
+// the result of comparing each output to the expected output.
+//
+// If a function panics, or the case expects a panic, then a single result
+// is returned for the panic instead.
+func (tab table) evaluate(i int, c *config) (rs []Result) {
+	var in, out, res []reflect.Value
+	var p *panicked
+
+	cv := tab.cvs.Index(i)
+	defer func() {
+		for j := range rs {
+			rs[j].Label = name(cv)
+			rs[j].Case = i
 		}
-		res = f1v.Call(in)
+	}()
 
-		for i := 0; i < nOut; i++ {
-			ri := res[i]
-			oi := out[i]
-			if err := handleSubtest(i, ri, oi, tol); err != nil {
-				t.Error(err)
-			}
+	c = caseConfig(cv, c)
+	in = sliceFrom(cv, 1, tab.nIn)
+	if want := special(cv, "panic"); expectsPanic(want) {
+		_, p = call(tab.f1v, in)
+		return []Result{handlePanic(p, want)}
+	}
+
+	if tab.f2v.IsNil() {
+		out = sliceFrom(cv, 1+tab.nIn, tab.nOut)
+	} else if out, p = call(tab.f2v, in); p != nil {
+		return []Result{unexpectedPanic("f2", p)}
+	}
+	if res, p = call(tab.f1v, in); p != nil {
+		return []Result{unexpectedPanic("f1", p)}
+	}
+
+	rs = make([]Result, tab.nOut)
+	for j := range rs {
+		rs[j] = compareOutput(j, res[j], out[j], outputConfig(in, out[j], c))
+	}
+	return
+}
+
+// caseConfig returns the config for the case cv, which is c with the
+// tolerance replaced by the Tolerance field of cv if it is set.
+func caseConfig(cv reflect.Value, c *config) *config {
+	if tol := special(cv, "tol"); tol.IsValid() && !tol.IsZero() {
+		cc := *c
+		cc.setTolerance(tol.Interface())
+		return &cc
+	}
+	return c
+}
+
+// outputConfig returns the config for comparing an output to the expected
+// output want, given the inputs in. If c has a ToleranceFunc, it is called
+// to give the tolerance.
+func outputConfig(in []reflect.Value, want reflect.Value, c *config) *config {
+	if c.tolFunc == nil {
+		return c
+	}
+	return c.withTolerance(validateTolerance(c.tolFunc(interfaces(in), valueOf(want))))
+}
+
+// compareOutput compares output i to the expected output, using
+// the comparer for output i if one is set in c.
+func compareOutput(i int, ri, oi reflect.Value, c *config) (r Result) {
+	r.Output = i
+	r.Got = valueOf(ri)
+	r.Want = valueOf(oi)
+
+	if f, ok := c.comparers[i]; ok {
+		if r.Ok = f(r.Got, r.Want); !r.Ok {
+			r.Err = fmt.Errorf("[%v]: Got %v, want %v", i, r.Got, r.Want)
 		}
-	})
+		return
+	}
+
+	if match, ok := c.errors[i]; ok {
+		oi = matchErrors(oi, match)
+	}
+	res := compare(ri, oi, c)
+	if res.Err != nil {
+		r.Err = fmt.Errorf("[%v]: %v", i, res.Err)
+		return
+	}
+	r.Ok = res.Ok
+	r.Mismatches = res.Mismatches
+	r.Summary = res.Summary
+	if res.Numerical {
+		r.AbsoluteError = valueOf(res.AbsoluteError)
+		r.RelativeError = valueOf(res.RelativeError)
+	}
+	if !res.Ok && c.unordered && len(res.Mismatches) == 0 && res.Summary == nil {
+		// an unmatched element of a slice compared as a multiset may be
+		// within the output, so it is located by finding the first mismatch
+		cc := *c
+		cc.collect, cc.maxMismatches, cc.summary = true, 1, 0
+		res.Mismatches = compare(ri, oi, &cc).Mismatches
+	}
+	r.Err = handleSubtest(i, ri, oi, res)
+	return
+}
+
+// valueOf returns the value held by v, or nil if v is invalid. If v was
+// obtained from an unexported struct field, then v itself is returned, which
+// is formatted by the fmt package as the value it holds.
+func valueOf(v reflect.Value) interface{} {
+	switch {
+	case !v.IsValid():
+		return nil
+	case !v.CanInterface():
+		return v
+	}
+	return v.Interface()
 }
 
-// handleSubtest returns an error if a subtest fails.
-func handleSubtest(i int, ri, oi reflect.Value, tol float64) (err error) {
-	res := equal(ri, oi, tol)
+// handleSubtest returns an error describing the failure of
+// output i of a subtest, given the result of comparing the output.
+func handleSubtest(i int, ri, oi reflect.Value, res EqualResult) (err error) {
 	if res.Ok {
 		return
 	}
+	if res.Counterexample != nil {
+		defer func() {
+			err = fmt.Errorf("%v\n\tcounterexample: %v", err, caseLiteral("counterexample", res.Counterexample))
+		}()
+	}
+	if res.Summary != nil || len(res.Mismatches) > 0 {
+		var msgs []string
+		if res.Summary != nil {
+			msgs = append(msgs, fmt.Sprintf("[%v]: %v", i, res.Summary))
+		}
+		for _, m := range res.Mismatches {
+			msgs = append(msgs, fmt.Sprintf("[%v]%v", i, m))
+		}
+		err = fmt.Errorf("%v", strings.Join(msgs, "\n"))
+		return
+	}
 	if res.LengthMismatch {
 		err = fmt.Errorf("[%v]: Length mismatch", i)
 		return
@@ -88,6 +376,8 @@ func handleSubtest(i int, ri, oi reflect.Value, tol float64) (err error) {
 			err = fmt.Errorf("[%v]: Missing struct field %v", i, oi.Type().Field(missing).Name)
 		case reflect.Map:
 			err = fmt.Errorf("[%v]: Missing key %v", i, oi.MapKeys()[missing])
+		case reflect.Array, reflect.Slice:
+			err = fmt.Errorf("[%v][%v]: No match, want %v", i, missing, oi.Index(missing))
 		default:
 			err = fmt.Errorf("[%v]: Should never reach here", i)
 		}
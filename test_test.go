@@ -5,6 +5,7 @@
 package testutil_test
 
 import (
+	"fmt"
 	"math"
 	"testing"
 
@@ -111,6 +112,64 @@ func TestTest_Default(t *testing.T) {
 	Test(t, -inf, cases[4:], g)
 }
 
+func TestTestParallel(t *testing.T) {
+	cases := []struct {
+		Label         string
+		In1, In2, Out float64
+	}{
+		{"", 0, 0, 0},
+		{"", 1, 1, math.Sqrt2},
+		{"", 3, 4, 5},
+		{"", 3e200, 4e200, 5e200},
+		{"", inf, nan, inf},
+	}
+
+	hypot := func(x, y float64) float64 {
+		if math.IsInf(x, 0) || math.IsInf(y, 0) {
+			return inf
+		}
+		x, y = math.Abs(x), math.Abs(y)
+		if x < y {
+			x, y = y, x
+		}
+		if x == 0 {
+			return 0
+		}
+		y /= x
+		return x * math.Sqrt(1+y*y)
+	}
+
+	TestParallel(t, 2, 1e-15, cases, math.Hypot)
+	TestParallel(t, 0, 1e-15, cases, hypot, math.Hypot)
+	TestParallel(t, len(cases)+1, 1e-15, cases, hypot)
+}
+
+// harnessTB is a home-grown implementation of testing.TB
+// which records errors instead of failing the test.
+type harnessTB struct {
+	testing.TB
+	errs []string
+}
+
+func (t *harnessTB) Error(args ...interface{}) { t.errs = append(t.errs, fmt.Sprint(args...)) }
+
+func TestTest_TB(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out float64
+	}{
+		{"Zero", 0, 0},
+		{"One", 1, 2},
+		{"", 4, 3},
+	}
+
+	h := &harnessTB{TB: t}
+	Test(h, nil, cases, math.Sqrt)
+	if want := []string{"One: [0]: Got 1, want 2 (δ=-0.5)", "[0]: Got 2, want 3 (δ=-0.3333333333333333)"}; !Equal(h.errs, want, nil).Ok {
+		t.Errorf("Error: wanted %q, got %q", want, h.errs)
+	}
+}
+
 // The following tests fail by design and are used to
 // check the error messages produced are as expected.
 
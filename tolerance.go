@@ -0,0 +1,383 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tolerance represents a numerical tolerance combining absolute, relative
+// and ULP bounds on the difference between an actual value x and an expected
+// value y. It can be used anywhere a tolerance is accepted.
+//
+// Each bound that is non-zero is checked, and x equals y within the tolerance
+// if it satisfies any of them, or all of them if All is true. If no bounds are
+// set, x must equal y exactly. For example, Tolerance{Abs: 1e-12, Rel: 1e-9}
+// is a relative tolerance with an absolute floor for y close to zero.
+//
+// A plain number t is equivalent to Tolerance{Rel: t}. As the relative error is
+// undefined for y = 0, Rel is used as an absolute bound, |x| < Rel, if y = 0
+// and Abs is not set.
+type Tolerance struct {
+	Abs float64 // |x - y| ≤ Abs
+	Rel float64 // |x - y| ≤ Rel * |y|
+	ULP ULP     // x and y are at most ULP units in the last place apart
+	All bool    // if true, x must satisfy every bound; otherwise any bound
+}
+
+// isZero reports whether t has no bounds set, i.e. requires exact equality.
+func (t Tolerance) isZero() bool {
+	return t.Abs == 0 && t.Rel == 0 && t.ULP == 0
+}
+
+// scalar returns the relative bound of t, or its absolute bound
+// if it has no relative bound.
+func (t Tolerance) scalar() float64 {
+	if t.Rel != 0 {
+		return t.Rel
+	}
+	return t.Abs
+}
+
+// ULP represents a tolerance in units in the last place, i.e. a maximum
+// number of representable floating-point values between an actual and an
+// expected value. It can be used anywhere a tolerance is accepted, and is
+// equivalent to a Tolerance with only the ULP bound set.
+//
+// ULPs are counted in the precision of the values being compared, so float32
+// values are compared in float32 ULPs and float64 values in float64 ULPs.
+// The ULP between integers is 1.
+type ULP uint64
+
+// ToleranceFunc returns the tolerance for comparing an output of a case,
+// given the inputs of the case and the expected output want. It can be used
+// anywhere a tolerance is accepted, to give bounds which depend on the
+// arguments, e.g. that grow with the condition number of a function.
+//
+// An unnamed func with the same signature is also accepted as a ToleranceFunc.
+// When used with Equal or EqualWith, in is empty and want is the expected value.
+type ToleranceFunc func(in []interface{}, want interface{}) Tolerance
+
+// validateTolerance ensures the tolerance passed is sensibly valued.
+// It accepts a Tolerance, a ULP or any number, of which the magnitude
+// is used as a relative tolerance. Anything else is treated as zero.
+func validateTolerance(tolerance interface{}) (tol Tolerance) {
+	switch t := tolerance.(type) {
+	case Tolerance:
+		tol = t
+		tol.Abs = sanitize(tol.Abs)
+		tol.Rel = sanitize(tol.Rel)
+		return
+	case ULP:
+		tol.ULP = t
+		return
+	}
+	t := reflect.ValueOf(tolerance)
+	switch kind := t.Kind(); kind {
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		tol.Rel = sanitize(t.Convert(floatType).Interface().(float64))
+	case reflect.Complex64, reflect.Complex128:
+		tol.Rel = sanitize(cmplx.Abs(t.Convert(complexType).Interface().(complex128)))
+	}
+	return
+}
+
+// sanitize returns the magnitude of the bound x, or 0 if x is NaN.
+func sanitize(x float64) float64 {
+	if math.IsNaN(x) {
+		return 0
+	}
+	return math.Abs(x)
+}
+
+// within reports whether x is within the tolerance tol of y, given their
+// difference diff and distance in ULPs, with the relative error defined by p.
+// Neither x nor y can be NaN, and x must differ from y, with y finite.
+func (tol Tolerance) within(x, y, diff float64, ulps uint64, p RelativePolicy) bool {
+	n, ok := 0, 0
+	check := func(b bool) {
+		n++
+		if b {
+			ok++
+		}
+	}
+	if tol.Abs > 0 {
+		check(math.Abs(diff) <= tol.Abs)
+	}
+	if tol.Rel > 0 {
+		switch {
+		case p == RelativeLog:
+			check(math.Abs(logRatio(x, y)) <= tol.Rel)
+		case y == 0 && tol.Abs == 0 && p == RelativeExpected:
+			check(math.Abs(diff) < tol.Rel)
+		default:
+			check(math.Abs(diff) <= tol.Rel*scale(x, y, p))
+		}
+	}
+	if tol.ULP > 0 {
+		check(!math.IsInf(x, 0) && ulps <= uint64(tol.ULP))
+	}
+	if tol.All {
+		return n > 0 && ok == n
+	}
+	return ok > 0
+}
+
+// scale returns the magnitude relative to which the difference
+// between x and y is measured under the policy p.
+func scale(x, y float64, p RelativePolicy) float64 {
+	switch p {
+	case RelativeMax:
+		return math.Max(math.Abs(x), math.Abs(y))
+	case RelativeMean:
+		return (math.Abs(x) + math.Abs(y)) / 2
+	}
+	return math.Abs(y)
+}
+
+// relativeError returns the error in x relative to y under the policy p,
+// given their difference diff. If it is undefined, because the scale is
+// zero, diff is returned instead.
+func relativeError(x, y, diff float64, p RelativePolicy) float64 {
+	switch p {
+	case RelativeLog:
+		return logRatio(x, y)
+	case RelativeExpected:
+		if y == 0 {
+			return diff
+		}
+		return diff / y
+	}
+	if s := scale(x, y, p); s != 0 {
+		return diff / s
+	}
+	return diff
+}
+
+// logRatio returns log(x/y), or +Inf if x and y have
+// different signs or either is zero (but not both).
+func logRatio(x, y float64) float64 {
+	switch {
+	case x == y:
+		return 0
+	case x == 0 || y == 0 || math.Signbit(x) != math.Signbit(y):
+		return math.Inf(1)
+	}
+	return math.Log(math.Abs(x)) - math.Log(math.Abs(y))
+}
+
+// fieldTag represents the settings given by the testutil tag of a field
+// in a struct being compared. For example, the tags
+//
+//	Iterations int     `testutil:"exact"`
+//	Residual   float64 `testutil:"abs=1e-12,rel=1e-9"`
+//	Error      float64 `testutil:"ulp=4"`
+//	Elapsed    float64 `testutil:"ignore"`
+//
+// compare Iterations exactly, Residual within an absolute or relative bound,
+// Error within 4 ULPs and ignore Elapsed. The keys abs, rel, ulp and all set
+// the bounds of a Tolerance (see Tolerance), which replaces the tolerance
+// used for that field and anything it contains. Unknown keys are an error.
+type fieldTag struct {
+	tol    Tolerance
+	hasTol bool
+	ignore bool
+}
+
+// fieldTags caches the parsed fieldTags of each struct type.
+var fieldTags sync.Map // map[reflect.Type]parsedTags
+
+// parsedTags holds the parsed fieldTags of a struct type,
+// or the error if one of them is malformed.
+type parsedTags struct {
+	tags []fieldTag
+	err  error
+}
+
+// fieldTagsOf returns the parsed fieldTags of the fields of the struct type t,
+// or an error if a tag is malformed.
+func fieldTagsOf(t reflect.Type) ([]fieldTag, error) {
+	if p, ok := fieldTags.Load(t); ok {
+		return p.(parsedTags).tags, p.(parsedTags).err
+	}
+	var p parsedTags
+	p.tags = make([]fieldTag, t.NumField())
+	for i := range p.tags {
+		f := t.Field(i)
+		s := f.Tag.Get("testutil")
+		tag, err := parseFieldTag(s)
+		if err != nil {
+			p.tags, p.err = nil, fmt.Errorf("invalid tag %q for field %v of %v: %v", s, f.Name, t, err)
+			break
+		}
+		p.tags[i] = tag
+	}
+	fieldTags.Store(t, p)
+	return p.tags, p.err
+}
+
+// checkTags returns an error if the tag of a field of any struct type
+// which values of type t can contain, other than through interfaces,
+// is malformed.
+func checkTags(t reflect.Type) error {
+	return checkTagsOf(t, make(map[reflect.Type]bool))
+}
+
+func checkTagsOf(t reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return checkTagsOf(t.Elem(), seen)
+	case reflect.Map:
+		if err := checkTagsOf(t.Key(), seen); err != nil {
+			return err
+		}
+		return checkTagsOf(t.Elem(), seen)
+	case reflect.Struct:
+		tags, err := fieldTagsOf(t)
+		if err != nil {
+			return err
+		}
+		for i, tag := range tags {
+			if tag.ignore {
+				continue
+			}
+			if err := checkTagsOf(t.Field(i).Type, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseFieldTag parses the testutil tag s of a struct field.
+func parseFieldTag(s string) (tag fieldTag, err error) {
+	if s == "" {
+		return
+	}
+	for _, kv := range strings.Split(s, ",") {
+		k, v := strings.TrimSpace(kv), ""
+		if i := strings.Index(k, "="); i >= 0 {
+			k, v = strings.TrimSpace(k[:i]), strings.TrimSpace(k[i+1:])
+		}
+		switch k {
+		case "ignore", "exact", "all":
+			if v != "" {
+				return tag, fmt.Errorf("unexpected value for key %q", k)
+			}
+		}
+		switch k {
+		case "ignore":
+			tag.ignore = true
+		case "exact":
+			tag.hasTol = true
+		case "all":
+			tag.tol.All, tag.hasTol = true, true
+		case "abs", "rel":
+			var x float64
+			if x, err = strconv.ParseFloat(v, 64); err != nil {
+				return
+			}
+			if k == "abs" {
+				tag.tol.Abs = sanitize(x)
+			} else {
+				tag.tol.Rel = sanitize(x)
+			}
+			tag.hasTol = true
+		case "ulp":
+			var n uint64
+			if n, err = strconv.ParseUint(v, 10, 64); err != nil {
+				return
+			}
+			tag.tol.ULP, tag.hasTol = ULP(n), true
+		default:
+			return tag, fmt.Errorf("unknown key %q", k)
+		}
+	}
+	return
+}
+
+// bitSize returns the size in bits of the floating-point type t,
+// or 0 if t is not a floating-point type.
+func bitSize(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return t.Bits()
+	}
+	return 0
+}
+
+// ulps returns the distance between x and y in units in the last place,
+// counted as floats of the given bit size, or as integers if bits is 0.
+// The signed zeros are at the same position, so that the distance between
+// the smallest positive and negative subnormal numbers is 2. The distance
+// to NaN is the maximum uint64.
+func ulps(x, y float64, bits int) uint64 {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return math.MaxUint64
+	}
+	var a, b int64
+	switch bits {
+	case 0:
+		return uint64(math.Abs(x - y))
+	case 32:
+		a, b = int64(ordered32(float32(x))), int64(ordered32(float32(y)))
+	default:
+		a, b = ordered64(x), ordered64(y)
+	}
+	if a < b {
+		a, b = b, a
+	}
+	return uint64(a) - uint64(b) // correct modulo 2^64 even if a - b overflows
+}
+
+// ulpsOf returns the distance diff in units in the last place of x, rounded
+// up, counted as floats of the given bit size.
+func ulpsOf(diff, x float64, bits int) uint64 {
+	var ulp float64
+	if bits == 32 {
+		f := float32(math.Abs(x))
+		ulp = float64(math.Nextafter32(f, float32(math.Inf(1))) - f)
+	} else {
+		ulp = math.Nextafter(math.Abs(x), math.Inf(1)) - math.Abs(x)
+	}
+	switch n := math.Ceil(diff / ulp); {
+	case math.IsNaN(n) || n >= math.MaxUint64:
+		return math.MaxUint64
+	default:
+		return uint64(n)
+	}
+}
+
+// ordered64 maps the float64 x to an integer such that the order of
+// floats is preserved and adjacent floats map to adjacent integers.
+func ordered64(x float64) int64 {
+	i := int64(math.Float64bits(x))
+	if i < 0 { // negative, so flip the magnitude
+		i = math.MinInt64 - i
+	}
+	return i
+}
+
+// ordered32 maps the float32 x to an integer such that the order of
+// floats is preserved and adjacent floats map to adjacent integers.
+func ordered32(x float32) int32 {
+	i := int32(math.Float32bits(x))
+	if i < 0 { // negative, so flip the magnitude
+		i = math.MinInt32 - i
+	}
+	return i
+}
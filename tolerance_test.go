@@ -0,0 +1,342 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func TestEqual_ULP(t *testing.T) {
+	var (
+		one32     = float32(1)
+		next32    = math.Nextafter32(one32, 2)
+		next64    = math.Nextafter(1, 2)
+		tiny      = math.SmallestNonzeroFloat64
+		max64     = math.MaxFloat64
+		subnormal = math.Float64frombits(1 << 51) // 2^51 ULPs from 0
+	)
+
+	cases := []struct {
+		Label         string
+		In1, In2, In3 interface{}
+		Out1          bool
+		Out2          uint64
+	}{
+		{"Equal", 1., 1., ULP(0), true, 0},
+		{"Adjacent", next64, 1., ULP(1), true, 1},
+		{"Adjacent", next64, 1., ULP(0), false, 1},
+		{"Float32", next32, one32, ULP(1), true, 1},
+		{"Float32", float32(1.5), one32, ULP(1 << 22), true, 1 << 22},
+		{"Float64", 1.5, 1., ULP(1 << 51), true, 1 << 51},
+		{"SignCrossing", -tiny, tiny, ULP(2), true, 2},
+		{"SignCrossing", -tiny, tiny, ULP(1), false, 2},
+		{"Subnormal", subnormal, 0., ULP(1 << 51), true, 1 << 51},
+		{"Zeros", 0., math.Copysign(0, -1), ULP(10), false, 0},
+		{"Infinity", inf, inf, ULP(0), true, 0},
+		{"Infinity", inf, max64, ULP(1), false, 1},
+		{"Infinity", max64, inf, ULP(1), false, 1},
+		{"Infinity", 1., inf, ULP(1), false, 0x7ff0000000000000 - 0x3ff0000000000000},
+		{"Infinity", -inf, -inf, ULP(0), true, 0},
+		{"Infinity", -max64, inf, ULP(1 << 60), false, 1<<64 - 1<<53 - 1},
+		{"NaN", nan, nan, ULP(0), true, 0},
+		{"NaN", nan, 1., ULP(math.MaxUint64), false, math.MaxUint64},
+		{"Integer", 10, 12, ULP(2), true, 2},
+		{"Integer", uint8(12), uint8(10), ULP(1), false, 2},
+		{"Complex", complex(next64, 1), complex(1, next64), ULP(1), true, 1},
+		{"Complex64", complex(next32, 2), complex(one32, 2), ULP(0), false, 1},
+		{"Slice", []float64{1, next64}, []float64{next64, 1}, ULP(1), true, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			res := Equal(c.In1, c.In2, c.In3)
+			if res.Ok != c.Out1 || (!res.Ok && res.ULPError != c.Out2) {
+				t.Errorf("Error: wanted %v (%v ULPs), got %v (%v ULPs)", c.Out1, c.Out2, res.Ok, res.ULPError)
+			}
+		})
+	}
+}
+
+func TestTest_ULP(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out float32
+	}{
+		{"", 1, 1},
+		{"", 2, math.Nextafter32(float32(math.Sqrt2), 0)},
+	}
+	sqrt := func(x float32) float32 { return float32(math.Sqrt(float64(x))) }
+	Test(t, ULP(1), cases, sqrt)
+}
+
+func TestEqual_Tolerance(t *testing.T) {
+	next := math.Nextafter(1, 2)
+
+	cases := []struct {
+		Label         string
+		In1, In2, In3 interface{}
+		Out           bool
+	}{
+		{"None", 1., 1., Tolerance{}, true},
+		{"None", next, 1., Tolerance{}, false},
+		{"Abs", 1.1, 1., Tolerance{Abs: 0.2}, true},
+		{"Abs", 1.1, 1., Tolerance{Abs: 0.01}, false},
+		{"Abs", 1e-10, 0., Tolerance{Abs: 1e-9}, true},
+		{"Abs", -1e-10, 0., Tolerance{Abs: -1e-9}, true},
+		{"Rel", 101., 100., Tolerance{Rel: 0.01}, true},
+		{"Rel", 1.02, 1., Tolerance{Rel: 0.01}, false},
+		{"Rel", 1e-3, 0., Tolerance{Rel: 0.01}, true},
+		{"Rel", 1e-3, 0., 0.01, true},
+		{"Rel", 1e-3, 0., Tolerance{Rel: 0.01, Abs: 1e-4}, false},
+		{"ULP", next, 1., Tolerance{ULP: 1}, true},
+		{"AbsOrRel", 1e-10, 1e-20, Tolerance{Abs: 1e-9, Rel: 1e-6}, true},
+		{"AbsOrRel", 1e10 + 1, 1e10, Tolerance{Abs: 1e-9, Rel: 1e-6}, true},
+		{"AbsOrRel", 1.1, 1., Tolerance{Abs: 1e-9, Rel: 1e-6}, false},
+		{"Infinity", 5., inf, Tolerance{Abs: 1e-9}, false},
+		{"Infinity", math.MaxFloat64, inf, Tolerance{Rel: 1, ULP: 1}, false},
+		{"Infinity", -inf, -inf, Tolerance{Abs: 1e-9}, true},
+		{"AbsAndRel", 1e-10, 1e-20, Tolerance{Abs: 1e-9, Rel: 1e-6, All: true}, false},
+		{"AbsAndRel", 1 + 1e-10, 1., Tolerance{Abs: 1e-9, Rel: 1e-6, All: true}, true},
+		{"AbsAndULP", next, 1., Tolerance{Abs: 1e-9, ULP: 1, All: true}, true},
+		{"AbsAndULP", 1 + 1e-10, 1., Tolerance{Abs: 1e-9, ULP: 1, All: true}, false},
+		{"NaN", 1., 1.5, Tolerance{Abs: nan, Rel: 0.5}, true},
+		{"Infinite", inf, 1., Tolerance{Abs: math.MaxFloat64}, false},
+		{"Complex", complex(1e-10, 1), complex(0, 1), Tolerance{Abs: 1e-9}, true},
+		{"Complex", complex(1e-8, 1), complex(0, 1), Tolerance{Rel: 1e-9}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := Equal(c.In1, c.In2, c.In3); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestTest_Tolerance(t *testing.T) {
+	cases := []struct {
+		Label   string
+		In, Out float64
+	}{
+		{"Zero", math.Pi, 0},
+		{"Small", 1e-3, math.Sin(1e-3) * (1 + 1e-12)},
+		{"Large", 1e3, math.Sin(1e3) * (1 - 1e-12)},
+	}
+	Test(t, Tolerance{Abs: 1e-15, Rel: 1e-9}, cases, math.Sin)
+}
+
+type solverResult struct {
+	Iterations int
+	Residual   float64   `testutil:"abs=1e-9"`
+	Root       float64   `testutil:"rel=1e-12"`
+	Elapsed    float64   `testutil:"ignore"`
+	Trace      []float64 `testutil:"ulp=2"`
+}
+
+func TestEqual_FieldTags(t *testing.T) {
+	want := solverResult{10, 0, 2, 0.5, []float64{1, 2}}
+	cases := []struct {
+		Label string
+		In1   solverResult
+		In2   interface{}
+		Out   bool
+	}{
+		{"Equal", want, nil, true},
+		{"Ignore", solverResult{10, 0, 2, 7, []float64{1, 2}}, nil, true},
+		{"Abs", solverResult{10, 1e-10, 2, 0.5, []float64{1, 2}}, nil, true},
+		{"Abs", solverResult{10, 1e-8, 2, 0.5, []float64{1, 2}}, 1., false},
+		{"Rel", solverResult{10, 0, 2 + 1e-13, 0.5, []float64{1, 2}}, nil, true},
+		{"Rel", solverResult{10, 0, 2 + 1e-11, 0.5, []float64{1, 2}}, 1., false},
+		{"ULP", solverResult{10, 0, 2, 0.5, []float64{1, math.Nextafter(2, 3)}}, nil, true},
+		{"ULP", solverResult{10, 0, 2, 0.5, []float64{1, 2 + 1e-12}}, 1., false},
+		{"Untagged", solverResult{11, 0, 2, 0.5, []float64{1, 2}}, nil, false},
+		{"Untagged", solverResult{11, 0, 2, 0.5, []float64{1, 2}}, 0.1, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			if res := Equal(c.In1, want, c.In2); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestEqual_FieldTagsInvalid(t *testing.T) {
+	type bad struct {
+		X float64 `testutil:"rel=x"`
+	}
+	type unknown struct {
+		X float64 `testutil:"relative=1e-9"`
+	}
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+	}{
+		{"Value", "rel=x", bad{1}},
+		{"Key", "relative", unknown{1}},
+		{"Nested", "rel=x", []interface{}{1, bad{1}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			res := EqualWith(c.In2, c.In2, WithMismatches(0))
+			if res.Ok || res.Err == nil || !strings.Contains(res.Err.Error(), c.In1.(string)) {
+				t.Errorf("Error: wanted error for tag %q, got %v", c.In1, res)
+			}
+		})
+	}
+}
+
+func TestCheck_FieldTagsInvalid(t *testing.T) {
+	type bad struct {
+		X float64 `testutil:"ulp=-1"`
+	}
+	cases := []struct {
+		Label string
+		In    float64
+		Out   []bad
+	}{
+		{"", 1, nil},
+	}
+	if _, err := Check(nil, cases, func(x float64) []bad { return nil }); err == nil {
+		t.Errorf("Error: wanted error for invalid tag")
+	}
+}
+
+func TestTest_CaseTolerance(t *testing.T) {
+	cases := []struct {
+		Label     string
+		In, Out   float64
+		Tolerance interface{}
+	}{
+		{"Default", 1, math.Sin(1) * (1 + 1e-12), nil},
+		{"Looser", 1e3, math.Sin(1e3) * (1 + 1e-6), 1e-5},
+		{"ULP", 2, math.Nextafter(math.Sin(2), 1), ULP(1)},
+		{"Tolerance", 3, math.Sin(3) + 1e-8, Tolerance{Abs: 1e-7}},
+	}
+	Test(t, 1e-9, cases, math.Sin)
+
+	var errs []string
+	TestWith(t, cases, []Func{math.Sin}, WithReporter(recorder(&errs)))
+	if len(errs) != 1 {
+		t.Errorf("Error: wanted 1 failure, got %q", errs)
+	}
+}
+
+func TestTest_CaseToleranceTagged(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    float64
+		Tol   float64 `testutil:"tol"`
+		Out   float64
+	}{
+		{"", 1, 0, math.Sin(1)},
+		{"", 2, 1e-6, math.Sin(2) * (1 - 1e-7)},
+	}
+	Test(t, nil, cases, math.Sin)
+	Test(t, nil, cases, math.Sin, math.Sin)
+}
+
+func TestTest_ToleranceOutput(t *testing.T) {
+	// a float64 field named Tolerance is an output, not a special field
+	bisect := func(a, b float64) (root, tolerance float64) { return (a + b) / 2, (b - a) / 2 }
+	cases := []struct {
+		Label          string
+		In1, In2       float64
+		Out, Tolerance float64
+	}{
+		{"", 0, 1, 0.5, 0.5},
+	}
+	Test(t, nil, cases, bisect)
+}
+
+func TestTest_ToleranceFunc(t *testing.T) {
+	// the condition number of sin(x) is |x cot x|
+	cond := func(in []interface{}, want interface{}) Tolerance {
+		x := in[0].(float64)
+		return Tolerance{Rel: 1e-15 * math.Max(1, math.Abs(x/math.Tan(x)))}
+	}
+	cases := []struct {
+		Label   string
+		In, Out float64
+	}{
+		{"Small", 1, math.Sin(1) * (1 + 1e-16)},
+		{"Large", 1e6, math.Sin(1e6) * (1 + 1e-10)},
+	}
+	Test(t, ToleranceFunc(cond), cases, math.Sin)
+	Test(t, cond, cases, math.Sin, math.Sin)
+
+	var errs []string
+	TestWith(t, cases, []Func{math.Sin}, WithTolerance(1e-14), WithReporter(recorder(&errs)))
+	if len(errs) != 1 {
+		t.Errorf("Error: wanted 1 failure, got %q", errs)
+	}
+}
+
+func TestEqual_ToleranceFunc(t *testing.T) {
+	rel := func(in []interface{}, want interface{}) Tolerance {
+		if len(in) != 0 {
+			t.Errorf("Error: wanted no inputs, got %v", in)
+		}
+		return Tolerance{Rel: math.Abs(want.(float64)) * 1e-3}
+	}
+	if res := Equal(1000.5, 1000., rel); !res.Ok {
+		t.Errorf("Error: wanted true, got %v", res)
+	}
+	if res := Equal(1.5, 1., rel); res.Ok {
+		t.Errorf("Error: wanted false, got %v", res)
+	}
+}
+
+func TestEqual_RelativePolicy(t *testing.T) {
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		In3      RelativePolicy
+		Out1     bool
+		Out2     float64
+	}{
+		{"Expected", 2., 1., RelativeExpected, false, 1},
+		{"Expected", 1., 2., RelativeExpected, true, -0.5},
+		{"Expected", 1., 0., RelativeExpected, false, 1},
+		{"Max", 2., 1., RelativeMax, true, 0.5},
+		{"Max", 1., 2., RelativeMax, true, -0.5},
+		{"Max", 1., 0., RelativeMax, false, 1},
+		{"Mean", 3., 1., RelativeMean, false, 1},
+		{"Mean", 1.4, 1., RelativeMean, true, 0.4 / 1.2},
+		{"Log", math.E, 1., RelativeLog, false, 1},
+		{"Log", 1.5, 1., RelativeLog, true, math.Log(1.5)},
+		{"Log", -1., 1., RelativeLog, false, inf},
+		{"Log", 1., 0., RelativeLog, false, inf},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			res := EqualWith(c.In1, c.In2, WithTolerance(0.5), WithRelativePolicy(c.In3))
+			if rel := res.RelativeError.Float(); res.Ok != c.Out1 || (rel != c.Out2 && math.Abs(rel-c.Out2) > 1e-15) {
+				t.Errorf("Error: wanted %v (δ=%v), got %v (δ=%v)", c.Out1, c.Out2, res.Ok, rel)
+			}
+		})
+	}
+}
+
+func TestEqual_RelativePolicyComplex(t *testing.T) {
+	modulus := WithComplexPolicy(ComplexModulus)
+	if res := EqualWith(2+0i, 1+0i, WithTolerance(0.5), WithRelativePolicy(RelativeMax)); !res.Ok {
+		t.Errorf("Error: wanted true, got %v", res)
+	}
+	if res := EqualWith(1i, 1+0i, WithTolerance(0.5), WithRelativePolicy(RelativeLog), modulus); res.Ok {
+		t.Errorf("Error: wanted false, got %v", res)
+	}
+	if res := EqualWith(1.1+0.1i, 1+0i, WithTolerance(0.2), WithRelativePolicy(RelativeLog), modulus); !res.Ok {
+		t.Errorf("Error: wanted true, got %v", res)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import "reflect"
+
+// isUnordered reports whether the slice or array
+// at the current path is compared as a multiset.
+func (c *config) isUnordered() bool {
+	if c.cmp == nil {
+		return c.unordered && len(c.unorderedPaths) == 0
+	}
+	return c.unorderedAt(c.cmp.pathString())
+}
+
+// unorderedAt reports whether the slice or array
+// at path is compared as a multiset.
+func (c *config) unorderedAt(path string) bool {
+	return c.unordered && (len(c.unorderedPaths) == 0 || matchPath(c.unorderedPaths, path))
+}
+
+// equalUnordered reports whether the slices or arrays xv and yv, which have
+// equal lengths, are equal as multisets, i.e. each element of yv equals a
+// distinct element of xv within the tolerance.
+//
+// As equality within a tolerance is not transitive, the elements cannot
+// simply be sorted. Instead, a maximum matching is found in the bipartite
+// graph of equal pairs of elements, by augmenting paths, and every element
+// of yv must be matched.
+func equalUnordered(xv, yv reflect.Value, c *config) (res EqualResult) {
+	n := xv.Len()
+
+	// equals[j] holds the indices of the elements of xv equal to yv[j],
+	// which are compared at the path of yv[j]
+	p := probe(c)
+	equals := make([][]int, n)
+	for j := range equals {
+		for i := 0; i < n; i++ {
+			if equalAt(step{index: j}, xv.Index(i), yv.Index(j), p).Ok {
+				equals[j] = append(equals[j], i)
+			}
+		}
+	}
+
+	// matched[i] is the index of the element of yv matched to xv[i], or -1
+	matched := make([]int, n)
+	for i := range matched {
+		matched[i] = -1
+	}
+
+	// augment looks for a path from yv[j] which alternates between unmatched
+	// and matched pairs and ends at an unmatched element of xv, and swaps the
+	// pairs along it, so that one more element is matched.
+	var augment func(j int, seen []bool) bool
+	augment = func(j int, seen []bool) bool {
+		for _, i := range equals[j] {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+			if matched[i] < 0 || augment(matched[i], seen) {
+				matched[i] = j
+				return true
+			}
+		}
+		return false
+	}
+
+	res.Ok = true
+	for j := 0; j < n; j++ {
+		if augment(j, make([]bool, n)) {
+			continue
+		}
+		if res.Ok {
+			res.Ok = false
+			res.MissingValue = true
+			res.Position = j
+		}
+		unmatched(step{index: j}, yv.Index(j), c)
+		if !more(c) {
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2020, Jack Parkinson. All rights reserved.
+// Use of this source code is governed by the BSD 3-Clause
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"math/cmplx"
+	"testing"
+
+	. "github.com/scientificgo/testutil"
+)
+
+func TestEqual_Unordered(t *testing.T) {
+	type point struct{ X, Y float64 }
+	p, q := &point{1, 2}, &point{1, 3}
+	cases := []struct {
+		Label    string
+		In1, In2 interface{}
+		In3      []Option
+		Out      bool
+	}{
+		{"Ordered", []float64{1, 2, 3}, []float64{3, 1, 2}, nil, false},
+		{"Permuted", []float64{1, 2, 3}, []float64{3, 1, 2}, []Option{WithUnordered()}, true},
+		{"Duplicates", []float64{1, 1, 2}, []float64{1, 2, 2}, []Option{WithUnordered()}, false},
+		{"Length", []float64{1, 2}, []float64{1, 2, 2}, []Option{WithUnordered()}, false},
+		// x[0] equals both y[0] and y[1], but x[1] only equals y[0],
+		// so a greedy match of x[0] to y[0] must be undone
+		{"Assignment", []float64{1.0005, 0.9995}, []float64{1, 1.001}, []Option{WithUnordered()}, true},
+		{"Complex", []complex128{1i, -1i, 1}, []complex128{1, 1i * (1 + 1e-12), -1i}, []Option{WithUnordered()}, true},
+		{"Array", [3]int{1, 2, 3}, [3]int{2, 3, 1}, []Option{WithUnordered()}, true},
+		{"Nested", [][]float64{{1, 2}, {3}}, [][]float64{{3}, {2, 1}}, []Option{WithUnordered()}, true},
+		{"Paths", [][]float64{{1, 2}, {3}}, [][]float64{{2, 1}, {3}}, []Option{WithUnordered("[*]")}, true},
+		{"PathsOrdered", [][]float64{{1, 2}, {3}}, [][]float64{{3}, {2, 1}}, []Option{WithUnordered("[*]")}, false},
+		{"PathsNested", [][]float64{{1, 2}, {3}}, [][]float64{{3}, {2, 1}}, []Option{WithUnordered("", "[*]")}, true},
+		{"PathsIgnored", []point{{1, 5}, {2, 6}}, []point{{2, 0}, {1, 0}}, []Option{WithUnordered(), WithIgnorePaths("[*].Y")}, true},
+		{"Pointers", []*point{p, p}, []*point{q, q}, []Option{WithUnordered()}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			opts := append([]Option{WithTolerance(1e-3)}, c.In3...)
+			if res := EqualWith(c.In1, c.In2, opts...); res.Ok != c.Out {
+				t.Errorf("Error: wanted %v, got %v", c.Out, res)
+			}
+		})
+	}
+}
+
+func TestEqual_UnorderedMismatches(t *testing.T) {
+	res := EqualWith([]float64{1, 5, 6}, []float64{2, 1, 3}, WithUnordered(), WithMismatches(0))
+	var got []string
+	for _, m := range res.Mismatches {
+		got = append(got, m.String())
+	}
+	if want := []string{"[0]: No match, want 2", "[2]: No match, want 3"}; res.Ok || res.Position != 0 || !Equal(got, want, nil).Ok {
+		t.Errorf("Error: wanted %q, got %q", want, got)
+	}
+
+	// an unmatched element of a field does not make the field missing
+	type roots struct{ Roots []float64 }
+	if res := EqualWith(roots{[]float64{2, 3}}, roots{[]float64{1, 2}}, WithUnordered()); res.Ok || res.MissingValue {
+		t.Errorf("Error: wanted a mismatch of .Roots, got %v", res)
+	}
+}
+
+func TestTestWith_Unordered(t *testing.T) {
+	type roots struct {
+		Roots []complex128
+	}
+	// the roots of z^3 - 1
+	cube := func(n int) roots {
+		r := make([]complex128, n)
+		for k := range r {
+			r[k] = cmplx.Rect(1, 2*cmplx.Phase(-1)*float64(k)/float64(n))
+		}
+		return roots{r}
+	}
+	w := cmplx.Rect(1, 2*cmplx.Phase(-1)/3)
+	cases := []struct {
+		Label string
+		In    int
+		Out   roots
+	}{
+		{"", 3, roots{[]complex128{w * w, 1, w}}},
+	}
+	TestWith(t, cases, []Func{cube}, WithTolerance(1e-12), WithUnordered(".Roots"))
+
+	var errs []string
+	TestWith(t, cases, []Func{cube}, WithTolerance(1e-12), WithReporter(recorder(&errs)))
+	if len(errs) != 1 {
+		t.Errorf("Error: wanted 1 failure, got %q", errs)
+	}
+
+	// an unmatched element within an output is located by its path
+	half := func(n int) roots { return roots{[]complex128{2, 3}} }
+	for _, opt := range []Option{WithUnordered(), WithUnordered(".Roots")} {
+		errs = nil
+		TestWith(t, []struct {
+			Label string
+			In    int
+			Out   roots
+		}{{"", 2, roots{[]complex128{1, 2}}}}, []Func{half}, opt, WithReporter(recorder(&errs)))
+		if want := "[0].Roots[0]: No match, want (1+0i)"; len(errs) != 1 || errs[0] != want {
+			t.Errorf("Error: wanted %q, got %q", want, errs)
+		}
+	}
+
+	// an unmatched element of a slice output is reported by its index
+	tests := []struct {
+		Label   string
+		In, Out []float64
+	}{
+		{"", []float64{1, 2, 3}, []float64{3, 1, 4}},
+	}
+	id := func(x []float64) []float64 { return x }
+	errs = nil
+	TestWith(t, tests, []Func{id}, WithUnordered(), WithReporter(recorder(&errs)))
+	if want := "[0][2]: No match, want 4"; len(errs) != 1 || errs[0] != want {
+		t.Errorf("Error: wanted %q, got %q", want, errs)
+	}
+}